@@ -0,0 +1,294 @@
+// sdl2-life
+// by Brian C. Lane <bcl@brianlane.com>
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+// Canvas is the surface that the world and status text are drawn on
+// SDLCanvas draws to the window, ImageCanvas draws into memory without needing a display.
+type Canvas interface {
+	// Clear fills the whole canvas with a color
+	Clear(c RGBAColor)
+	// FillRect fills a rectangle with a color
+	FillRect(x, y, w, h int32, c RGBAColor)
+	// FontHeight returns the height of a line of text
+	FontHeight() int
+	// TextSize returns the width and height of the text before it is rotated
+	TextSize(text string) (int, int, error)
+	// DrawText draws the text with the top left corner of its bounding box at x, y
+	// The text is rotated clockwise by angle, which is one of 0, 90, 180, or 270.
+	DrawText(text string, x, y int32, angle int) error
+	// DrawCells draws an image with one pixel per cell, with its top left corner at x, y
+	// Each pixel is scaled up to a cellSize square, when border is true the edges of the
+	// squares are drawn in the bg color.
+	DrawCells(cells *image.RGBA, x, y int32, cellSize int, border bool, bg RGBAColor) error
+	// Present shows everything drawn since the last Present
+	Present()
+}
+
+// SDLCanvas draws using an SDL renderer and TTF font
+type SDLCanvas struct {
+	renderer *sdl.Renderer
+	font     *ttf.Font
+	last     RGBAColor    // Current draw color of the renderer
+	texture  *sdl.Texture // Streaming texture with one texel per cell
+	grid     *sdl.Texture // Cell border overlay for the texture
+	gridKey  gridKey      // What the grid was made for
+}
+
+// gridKey is the size and color of a grid overlay, it is made again when any of them change
+type gridKey struct {
+	columns, rows, cellSize int
+	bg                      RGBAColor
+}
+
+// NewSDLCanvas returns a Canvas that draws with the renderer and font
+func NewSDLCanvas(renderer *sdl.Renderer, font *ttf.Font) *SDLCanvas {
+	return &SDLCanvas{renderer: renderer, font: font}
+}
+
+// setColor changes the draw color, but only when it is different
+func (s *SDLCanvas) setColor(c RGBAColor) {
+	if c != s.last {
+		s.renderer.SetDrawColor(c.r, c.g, c.b, c.a)
+		s.last = c
+	}
+}
+
+// Clear fills the window with a color
+func (s *SDLCanvas) Clear(c RGBAColor) {
+	s.setColor(c)
+	s.renderer.Clear()
+}
+
+// FillRect fills a rectangle with a color
+func (s *SDLCanvas) FillRect(x, y, w, h int32, c RGBAColor) {
+	s.setColor(c)
+	s.renderer.FillRect(&sdl.Rect{x, y, w, h})
+}
+
+// FontHeight returns the height of the font
+func (s *SDLCanvas) FontHeight() int {
+	return s.font.Height()
+}
+
+// TextSize returns the size of the text when it is rendered with the font
+func (s *SDLCanvas) TextSize(text string) (int, int, error) {
+	return s.font.SizeUTF8(text)
+}
+
+// DrawText renders the text in white and copies it to the window
+func (s *SDLCanvas) DrawText(text string, x, y int32, angle int) error {
+	surface, err := s.font.RenderUTF8Solid(text, sdl.Color{255, 255, 255, 255})
+	if err != nil {
+		return err
+	}
+	defer surface.Free()
+
+	texture, err := s.renderer.CreateTextureFromSurface(surface)
+	if err != nil {
+		return err
+	}
+	defer texture.Destroy()
+
+	w, h := surface.W, surface.H
+	switch angle {
+	case 90:
+		// Rotating around the top left corner moves the text to the left of it
+		return s.renderer.CopyEx(texture, nil, &sdl.Rect{x + h, y, w, h}, 90.0, &sdl.Point{0, 0}, sdl.FLIP_NONE)
+	case 180:
+		return s.renderer.CopyEx(texture, nil, &sdl.Rect{x, y, w, h}, 0.0, nil, sdl.FLIP_HORIZONTAL|sdl.FLIP_VERTICAL)
+	case 270:
+		return s.renderer.CopyEx(texture, nil, &sdl.Rect{x + h, y, w, h}, 90.0, &sdl.Point{0, 0}, sdl.FLIP_HORIZONTAL|sdl.FLIP_VERTICAL)
+	}
+	return s.renderer.Copy(texture, nil, &sdl.Rect{x, y, w, h})
+}
+
+// DrawCells writes the cells into a streaming texture and copies it to the window in one operation
+// The texture is scaled up to the size of the world when it is copied, and the cell borders
+// are drawn using a separate grid overlay texture.
+func (s *SDLCanvas) DrawCells(cells *image.RGBA, x, y int32, cellSize int, border bool, bg RGBAColor) error {
+	columns, rows := cells.Bounds().Dx(), cells.Bounds().Dy()
+	key := gridKey{columns, rows, cellSize, bg}
+	if s.texture == nil || key != s.gridKey {
+		if err := s.createTextures(key); err != nil {
+			return err
+		}
+	}
+
+	pixels, pitch, err := s.texture.Lock(nil)
+	if err != nil {
+		return err
+	}
+	for row := 0; row < rows; row++ {
+		copy(pixels[row*pitch:row*pitch+columns*4], cells.Pix[row*cells.Stride:])
+	}
+	s.texture.Unlock()
+
+	rect := &sdl.Rect{x, y, int32(columns * cellSize), int32(rows * cellSize)}
+	if err = s.renderer.Copy(s.texture, nil, rect); err != nil {
+		return err
+	}
+	if border {
+		return s.renderer.Copy(s.grid, nil, rect)
+	}
+	return nil
+}
+
+// createTextures sets up the streaming cell texture and the grid overlay
+func (s *SDLCanvas) createTextures(key gridKey) error {
+	s.Destroy()
+
+	// Scale the cells without blurring them
+	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "0")
+
+	var err error
+	s.texture, err = s.renderer.CreateTexture(uint32(sdl.PIXELFORMAT_RGBA32), sdl.TEXTUREACCESS_STREAMING, int32(key.columns), int32(key.rows))
+	if err != nil {
+		return err
+	}
+
+	// The grid is transparent except for the background colored edges of each cell
+	w := key.columns * key.cellSize
+	h := key.rows * key.cellSize
+	s.grid, err = s.renderer.CreateTexture(uint32(sdl.PIXELFORMAT_RGBA32), sdl.TEXTUREACCESS_STREAMING, int32(w), int32(h))
+	if err != nil {
+		s.Destroy()
+		return err
+	}
+	if err = s.grid.SetBlendMode(sdl.BLENDMODE_BLEND); err != nil {
+		s.Destroy()
+		return err
+	}
+	pixels, pitch, err := s.grid.Lock(nil)
+	if err != nil {
+		s.Destroy()
+		return err
+	}
+	size := key.cellSize
+	for y := 0; y < h; y++ {
+		edgeY := y%size == 0 || y%size == size-1
+		for x := 0; x < w; x++ {
+			edge := edgeY || x%size == 0 || x%size == size-1
+			i := y*pitch + x*4
+			if edge {
+				pixels[i], pixels[i+1], pixels[i+2], pixels[i+3] = key.bg.r, key.bg.g, key.bg.b, 255
+			} else {
+				pixels[i], pixels[i+1], pixels[i+2], pixels[i+3] = 0, 0, 0, 0
+			}
+		}
+	}
+	s.grid.Unlock()
+	s.gridKey = key
+
+	return nil
+}
+
+// Destroy frees the cell and grid textures
+func (s *SDLCanvas) Destroy() {
+	if s.texture != nil {
+		s.texture.Destroy()
+		s.texture = nil
+	}
+	if s.grid != nil {
+		s.grid.Destroy()
+		s.grid = nil
+	}
+}
+
+// Present shows the new frame in the window
+func (s *SDLCanvas) Present() {
+	s.renderer.Present()
+}
+
+// ImageCanvas draws into an in-memory image
+// There is no font, text is drawn as a white block the size it would be using fixed width
+// characters, with the first character in gray so that the direction of the text can be seen.
+type ImageCanvas struct {
+	Image      *image.RGBA
+	CharWidth  int
+	CharHeight int
+}
+
+// NewImageCanvas returns a Canvas that draws into a width x height image
+func NewImageCanvas(width, height int) *ImageCanvas {
+	return &ImageCanvas{
+		Image:      image.NewRGBA(image.Rect(0, 0, width, height)),
+		CharWidth:  4,
+		CharHeight: 8,
+	}
+}
+
+// Clear fills the image with a color
+func (m *ImageCanvas) Clear(c RGBAColor) {
+	draw.Draw(m.Image, m.Image.Bounds(), image.NewUniform(c.Color()), image.Point{}, draw.Src)
+}
+
+// FillRect fills a rectangle with a color, it is clipped to the edges of the image
+func (m *ImageCanvas) FillRect(x, y, w, h int32, c RGBAColor) {
+	r := image.Rect(int(x), int(y), int(x+w), int(y+h))
+	draw.Draw(m.Image, r, image.NewUniform(c.Color()), image.Point{}, draw.Src)
+}
+
+// FontHeight returns the height of a character
+func (m *ImageCanvas) FontHeight() int {
+	return m.CharHeight
+}
+
+// TextSize returns the size of the text's block
+func (m *ImageCanvas) TextSize(text string) (int, int, error) {
+	return len([]rune(text)) * m.CharWidth, m.CharHeight, nil
+}
+
+// DrawText draws the text's block, rotated by angle
+func (m *ImageCanvas) DrawText(text string, x, y int32, angle int) error {
+	w, h, _ := m.TextSize(text)
+	for ty := 0; ty < h; ty++ {
+		for tx := 0; tx < w; tx++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if tx < m.CharWidth {
+				c = color.RGBA{128, 128, 128, 255}
+			}
+			var px, py int
+			switch angle {
+			case 90:
+				px, py = h-1-ty, tx
+			case 180:
+				px, py = w-1-tx, h-1-ty
+			case 270:
+				px, py = ty, w-1-tx
+			default:
+				px, py = tx, ty
+			}
+			m.Image.SetRGBA(int(x)+px, int(y)+py, c)
+		}
+	}
+	return nil
+}
+
+// DrawCells draws each pixel of the cells as a cellSize square
+func (m *ImageCanvas) DrawCells(cells *image.RGBA, x, y int32, cellSize int, border bool, bg RGBAColor) error {
+	b := cells.Bounds()
+	for row := b.Min.Y; row < b.Max.Y; row++ {
+		for col := b.Min.X; col < b.Max.X; col++ {
+			r := image.Rect(0, 0, cellSize, cellSize).Add(image.Pt(int(x)+col*cellSize, int(y)+row*cellSize))
+			if border {
+				draw.Draw(m.Image, r, image.NewUniform(bg.Color()), image.Point{}, draw.Src)
+				r = r.Inset(1)
+			}
+			draw.Draw(m.Image, r, image.NewUniform(cells.RGBAAt(col, row)), image.Point{}, draw.Src)
+		}
+	}
+	return nil
+}
+
+// Present does nothing, the image is always up to date
+func (m *ImageCanvas) Present() {
+}
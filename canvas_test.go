@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Update the golden images in testdata")
+
+// readGolden reads a PNG from testdata
+func readGolden(name string) (image.Image, error) {
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+// writeGolden writes the image as a PNG in testdata
+func writeGolden(name string, img image.Image) error {
+	f, err := os.Create(filepath.Join("testdata", name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return png.Encode(f, img)
+}
+
+// compareImages returns an error describing the first pixel that is different
+func compareImages(expected, actual image.Image) error {
+	if expected.Bounds() != actual.Bounds() {
+		return fmt.Errorf("expected size %v, got %v", expected.Bounds(), actual.Bounds())
+	}
+	b := expected.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			er, eg, eb, ea := expected.At(x, y).RGBA()
+			ar, ag, ab, aa := actual.At(x, y).RGBA()
+			if er != ar || eg != ag || eb != ab || ea != aa {
+				return fmt.Errorf("pixel %d, %d: expected %v, got %v", x, y, expected.At(x, y), actual.At(x, y))
+			}
+		}
+	}
+	return nil
+}
+
+func TestDrawGolden(t *testing.T) {
+	saved := keepConfig(t)
+
+	var matrix = []struct {
+		name      string
+		rotate    int
+		statusTop bool
+		border    bool
+		color     bool
+		rule      string
+		texture   bool
+	}{
+		{"rotate-0.png", 0, false, false, false, "B3/S23", false},
+		{"rotate-0-top.png", 0, true, false, false, "B3/S23", false},
+		{"rotate-90.png", 90, false, false, false, "B3/S23", false},
+		{"rotate-90-top.png", 90, true, false, false, "B3/S23", false},
+		{"rotate-180.png", 180, false, false, false, "B3/S23", false},
+		{"rotate-180-top.png", 180, true, false, false, "B3/S23", false},
+		{"rotate-270.png", 270, false, false, false, "B3/S23", false},
+		{"rotate-270-top.png", 270, true, false, false, "B3/S23", false},
+		{"border.png", 0, false, true, false, "B3/S23", false},
+		{"age.png", 0, false, false, true, "B3/S23", false},
+		{"hex.png", 0, false, false, false, "B2/S34H", false},
+		{"hex-border.png", 0, false, true, false, "B2/S34H", false},
+		// The texture draws the same image as the individual cells
+		{"rotate-0.png", 0, false, false, false, "B3/S23", true},
+		{"rotate-90-top.png", 90, true, false, false, "B3/S23", true},
+		{"border.png", 0, false, true, false, "B3/S23", true},
+		{"age.png", 0, false, false, true, "B3/S23", true},
+		{"hex.png", 0, false, false, false, "B2/S34H", true},
+	}
+
+	for _, tt := range matrix {
+		cfg = saved
+		cfg.Width, cfg.Height, cfg.CellSize = 64, 48, 4
+		cfg.Rotate, cfg.StatusTop, cfg.Border, cfg.Color = tt.rotate, tt.statusTop, tt.border, tt.color
+		cfg.Colors = "#0000ff,#ff0000"
+		cfg.MaxAge = 10
+
+		canvas := NewImageCanvas(cfg.Width, cfg.Height)
+		g := &LifeGame{canvas: canvas, texture: tt.texture}
+		g.CalculateWorldSize()
+		g.InitializeColors()
+		g.ClearCells()
+		rule, err := ParseRulestring(tt.rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.rule = rule
+
+		// A glider in the top left corner, and an aging block in the bottom right corner
+		for i, xy := range [][2]int{{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2}} {
+			g.SetCellState(xy[0], xy[1], true)
+			g.cells[xy[1]][xy[0]].age = i * 2
+		}
+		for _, xy := range [][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+			x, y := g.columns-2+xy[0], g.rows-2+xy[1]
+			g.SetCellState(x, y, true)
+			g.cells[y][x].age = 10
+		}
+		g.Draw("life")
+
+		if *update {
+			if err := writeGolden(tt.name, canvas.Image); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		expected, err := readGolden(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := compareImages(expected, canvas.Image); err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+		}
+	}
+}
+
+func TestDrawCells(t *testing.T) {
+	keepConfig(t)
+	cfg.Width, cfg.Height, cfg.CellSize, cfg.Color = 64, 48, 4, true
+
+	// Drawing all of the cells at once gives the same image as drawing them one at a time
+	for _, border := range []bool{false, true} {
+		cfg.Border = border
+		g := randomGame(16, 10, 5)
+		var images []*image.RGBA
+		for _, texture := range []bool{false, true} {
+			canvas := NewImageCanvas(cfg.Width, cfg.Height)
+			g.canvas, g.texture = canvas, texture
+			g.Draw("life")
+			images = append(images, canvas.Image)
+		}
+		if err := compareImages(images[0], images[1]); err != nil {
+			t.Errorf("border=%v: %s", border, err)
+		}
+	}
+}
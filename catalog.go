@@ -0,0 +1,222 @@
+// sdl2-life
+// by Brian C. Lane <bcl@brianlane.com>
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// NamedRule is a rule in the built-in catalogue
+type NamedRule struct {
+	Name string
+	Rule string
+}
+
+// ruleCatalog lists the rules that can be used by name
+var ruleCatalog = []NamedRule{
+	{"Conway", "B3/S23"},
+	{"HighLife", "B36/S23"},
+	{"Day & Night", "B3678/S34678"},
+	{"Seeds", "B2/S"},
+	{"Life without Death", "B3/S012345678"},
+	{"Maze", "B3/S12345"},
+	{"Mazectric", "B3/S1234"},
+	{"Diamoeba", "B35678/S5678"},
+	{"Replicator", "B1357/S1357"},
+	{"2x2", "B36/S125"},
+	{"34 Life", "B34/S34"},
+	{"Amoeba", "B357/S1358"},
+	{"Anneal", "B4678/S35678"},
+	{"Assimilation", "B345/S4567"},
+	{"Coagulations", "B378/S235678"},
+	{"Coral", "B3/S45678"},
+	{"DotLife", "B3/S023"},
+	{"Flock", "B3/S12"},
+	{"Gnarl", "B1/S1"},
+	{"Honey Life", "B38/S238"},
+	{"Live Free or Die", "B2/S0"},
+	{"Long Life", "B345/S5"},
+	{"Morley", "B368/S245"},
+	{"Pedestrian Life", "B38/S23"},
+	{"Serviettes", "B234/S"},
+	{"Stains", "B3678/S235678"},
+	{"Walled Cities", "B45678/S2345"},
+	{"Bosco's Rule", "R5,C0,M1,S34..58,B34..45,NM"},
+	{"Immigration", "B3/S23"},
+	{"QuadLife", "B3/S23"},
+}
+
+// normalizeRuleName returns the name in lowercase without spaces or punctuation
+// & is the same as and, so Day & Night matches DayAndNight.
+func normalizeRuleName(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(name), "&", "and")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, name)
+}
+
+// CatalogRule returns the catalogue entry for a name, or false if there isn't one
+func CatalogRule(name string) (NamedRule, bool) {
+	n := normalizeRuleName(name)
+	if len(n) == 0 {
+		return NamedRule{}, false
+	}
+	for _, nr := range ruleCatalog {
+		if normalizeRuleName(nr.Name) == n {
+			return nr, true
+		}
+	}
+	return NamedRule{}, false
+}
+
+// catalogName returns the name of the catalogue rule that is the same as r, or an empty string
+func catalogName(r Rule) string {
+	for _, nr := range ruleCatalog {
+		if _, ok := speciesRules[nr.Name]; ok {
+			continue
+		}
+		cr, err := ParseRulestring(nr.Rule)
+		if err != nil {
+			continue
+		}
+		if reflect.DeepEqual(r, cr) {
+			return nr.Name
+		}
+	}
+	return ""
+}
+
+// NextCatalogRule returns the catalogue rule after the one named, or the first one
+func NextCatalogRule(name string) NamedRule {
+	for i, nr := range ruleCatalog {
+		if nr.Name == name {
+			return ruleCatalog[(i+1)%len(ruleCatalog)]
+		}
+	}
+	return ruleCatalog[0]
+}
+
+// ListRules writes the catalogue, and the rule files in -rule-dir, to w
+func ListRules(w io.Writer) error {
+	for _, nr := range ruleCatalog {
+		if species, ok := speciesRules[nr.Name]; ok {
+			fmt.Fprintf(w, "%-20s %s with %d species\n", nr.Name, nr.Rule, species)
+			continue
+		}
+		fmt.Fprintf(w, "%-20s %s\n", nr.Name, nr.Rule)
+	}
+	files, err := filepath.Glob(filepath.Join(cfg.RuleDir, "*.rule"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		fmt.Fprintf(w, "%-20s %s\n", strings.TrimSuffix(filepath.Base(f), ".rule"), f)
+	}
+	return nil
+}
+
+// UseRule switches the game to a rulestring, catalogue name, or rule file
+// Only -rule can be the path to a rule file. The name of the rule is kept in the pattern's
+// information, -rule is left alone so that it is used again for patterns without a rule.
+func (g *LifeGame) UseRule(rule string) error {
+	r, err := lookupRule(rule, rule == cfg.Rule)
+	if err != nil {
+		return err
+	}
+	// Rulestrings are saved in their canonical form, rule files by the name they were used with
+	g.info.Rule = rule
+	if len(r.Rulestring) > 0 {
+		g.info.Rule = r.Rulestring
+	} else if r.Species > 0 {
+		g.info.Rule = r.Name
+	}
+	g.SetRule(r)
+	return nil
+}
+
+// RuleName returns the name of the rule in use, it can be passed to UseRule to use it again
+func (g *LifeGame) RuleName() string {
+	if len(g.info.Rule) > 0 {
+		return g.info.Rule
+	}
+	return cfg.Rule
+}
+
+// RuleRequest is used to pass rule changes from the API to the game
+// If rule is empty the current rule is returned, otherwise the game switches to it.
+type RuleRequest struct {
+	rule  string
+	reply chan<- RuleReply
+}
+
+// RuleReply holds the result of a RuleRequest
+type RuleReply struct {
+	rule string
+	err  error
+}
+
+// HandleRuleRequest returns or changes the rule for the API
+func (g *LifeGame) HandleRuleRequest(req RuleRequest) {
+	if len(req.rule) > 0 {
+		if err := g.UseRule(req.rule); err != nil {
+			req.reply <- RuleReply{err: err}
+			return
+		}
+	}
+	req.reply <- RuleReply{rule: g.RuleName()}
+}
+
+// rulesHandler returns the list of rules that can be used by name
+func rulesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := ListRules(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ruleHandler returns the current rule with GET, or changes it with a POST of the rule
+func ruleHandler(rChan chan<- RuleRequest) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reply := make(chan RuleReply)
+		switch r.Method {
+		case "GET":
+			rChan <- RuleRequest{reply: reply}
+		case "POST":
+			data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPatternBytes))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			rule := strings.TrimSpace(string(data))
+			if len(rule) == 0 {
+				http.Error(w, "Empty rule", http.StatusBadRequest)
+				return
+			}
+			rChan <- RuleRequest{rule: rule, reply: reply}
+		default:
+			http.Error(w, "", http.StatusMethodNotAllowed)
+			return
+		}
+		result := <-reply
+		if result.err != nil {
+			http.Error(w, result.err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, result.rule)
+	}
+}
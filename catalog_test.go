@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCatalogRules(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, nr := range ruleCatalog {
+		if seen[normalizeRuleName(nr.Name)] {
+			t.Errorf("%s is in the catalogue more than once", nr.Name)
+		}
+		seen[normalizeRuleName(nr.Name)] = true
+		if _, err := ParseRulestring(nr.Rule); err != nil {
+			t.Errorf("%s: %s", nr.Name, err)
+		}
+	}
+}
+
+func TestLookupRuleName(t *testing.T) {
+	var matrix = []struct {
+		rule  string
+		name  string
+		birth []int
+	}{
+		{"HighLife", "HighLife", []int{3, 6}},
+		{"highlife", "HighLife", []int{3, 6}},
+		{"Day & Night", "Day & Night", []int{3, 6, 7, 8}},
+		{"DayAndNight", "Day & Night", []int{3, 6, 7, 8}},
+		{"B3/S23", "Conway", []int{3}},
+		{"B36/S23", "HighLife", []int{3, 6}},
+		{"B37/S23", "", []int{3, 7}},
+		{"Diamond", "Diamond", []int{4, 5}},
+	}
+
+	for _, tt := range matrix {
+		r, err := LookupRule(tt.rule)
+		if err != nil {
+			t.Errorf("%s: %s", tt.rule, err)
+			continue
+		}
+		if r.Name != tt.name {
+			t.Errorf("%s: expected name %q, got %q", tt.rule, tt.name, r.Name)
+		}
+		if len(r.Birth) != len(tt.birth) {
+			t.Errorf("%s: expected birth %v, got %v", tt.rule, tt.birth, r.Birth)
+		}
+		for _, n := range tt.birth {
+			if !r.Birth[n] {
+				t.Errorf("%s: expected birth %v, got %v", tt.rule, tt.birth, r.Birth)
+			}
+		}
+	}
+
+	if _, err := LookupRule("No Such Rule"); err == nil {
+		t.Errorf("expected an error for an unknown rule name")
+	}
+}
+
+func TestRuleNames(t *testing.T) {
+	saved := keepConfig(t)
+
+	// Names can be used in RLE headers
+	g := newTestGame(8, 8)
+	info, err := g.LoadPattern([]string{"x = 3, y = 1, rule = HighLife", "3o!"}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.ApplyPatternRule(info); err != nil {
+		t.Fatal(err)
+	}
+	if g.RuleName() != "B36/S23" || g.rule.Name != "HighLife" || cfg.Rule != saved.Rule {
+		t.Errorf("expected HighLife, got %s %s -rule %s", g.RuleName(), g.rule.Name, cfg.Rule)
+	}
+	g.NextFrame()
+	if !strings.HasSuffix(g.status, "  HighLife") {
+		t.Errorf("status does not include the rule name: %q", g.status)
+	}
+
+	// The API can change the rule, and return the current one
+	reply := make(chan RuleReply, 1)
+	g.HandleRuleRequest(RuleRequest{rule: "Seeds", reply: reply})
+	if result := <-reply; result.err != nil || result.rule != "B2/S" || g.rule.Name != "Seeds" {
+		t.Errorf("expected Seeds, got %v", result)
+	}
+	g.HandleRuleRequest(RuleRequest{rule: "B3", reply: reply})
+	if result := <-reply; result.err == nil || g.RuleName() != "B2/S" {
+		t.Errorf("expected an error, and Seeds, got %v %s", result, g.RuleName())
+	}
+	g.HandleRuleRequest(RuleRequest{reply: reply})
+	if result := <-reply; result.rule != "B2/S" {
+		t.Errorf("expected Seeds, got %v", result)
+	}
+
+	// Cycling through the rules wraps around to the start
+	if nr := NextCatalogRule("Conway"); nr.Name != "HighLife" {
+		t.Errorf("expected HighLife after Conway, got %s", nr.Name)
+	}
+	if nr := NextCatalogRule(ruleCatalog[len(ruleCatalog)-1].Name); nr.Name != "Conway" {
+		t.Errorf("expected Conway after the last rule, got %s", nr.Name)
+	}
+	if nr := NextCatalogRule(""); nr.Name != "Conway" {
+		t.Errorf("expected Conway for an unnamed rule, got %s", nr.Name)
+	}
+}
+
+func TestListRules(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ListRules(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"Day & Night", "B3678/S34678", "Diamond", "WireWorld"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("%s is missing from the list of rules", s)
+		}
+	}
+}
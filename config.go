@@ -0,0 +1,126 @@
+// sdl2-life
+// by Brian C. Lane <bcl@brianlane.com>
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// envPrefix is prepended to the upper-case flag names to get the environment variable names
+// eg. -status-top can be set with SDL2_LIFE_STATUS_TOP=true
+const envPrefix = "SDL2_LIFE_"
+
+// configPaths returns the paths to search for the config file, in XDG order
+func configPaths() []string {
+	var dirs []string
+	if home := os.Getenv("XDG_CONFIG_HOME"); len(home) > 0 {
+		dirs = append(dirs, home)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".config"))
+	}
+	if configDirs := os.Getenv("XDG_CONFIG_DIRS"); len(configDirs) > 0 {
+		dirs = append(dirs, filepath.SplitList(configDirs)...)
+	} else {
+		dirs = append(dirs, "/etc/xdg")
+	}
+
+	var paths []string
+	for _, d := range dirs {
+		paths = append(paths, filepath.Join(d, "sdl2-life", "config"))
+	}
+	return paths
+}
+
+// findConfig returns the first config file that exists, or an empty string
+func findConfig() string {
+	for _, p := range configPaths() {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// ParseConfig reads key=value lines from a config file
+// The keys are the flag names without the -, blank lines and lines starting with #
+// are skipped, and values may be quoted. A # after the value is part of the value.
+func ParseConfig(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		fields := strings.SplitN(line, "=", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected key = value", path, n)
+		}
+		key := strings.TrimSpace(fields[0])
+		value := strings.TrimSpace(fields[1])
+		if len(value) > 1 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// loadDefaults sets the flags that were not passed on the cmdline from the config file
+// and the environment. The environment takes precedence over the config file.
+func loadDefaults() error {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	path := cfg.ConfigFile
+	if len(path) == 0 {
+		path = findConfig()
+	}
+	if len(path) > 0 {
+		values, err := ParseConfig(path)
+		if err != nil {
+			return fmt.Errorf("Error reading config: %s", err)
+		}
+		for key, value := range values {
+			if flag.Lookup(key) == nil || key == "config" {
+				return fmt.Errorf("%s: unknown setting %q", path, key)
+			}
+			if set[key] {
+				continue
+			}
+			if err := flag.Set(key, value); err != nil {
+				return fmt.Errorf("%s: invalid value %q for %s: %s", path, value, key, err)
+			}
+		}
+	}
+
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] || f.Name == "config" || err != nil {
+			return
+		}
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if value, ok := os.LookupEnv(name); ok {
+			if e := flag.Set(f.Name, value); e != nil {
+				err = fmt.Errorf("invalid value %q for %s: %s", value, name, e)
+			}
+		}
+	})
+	return err
+}
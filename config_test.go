@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	data := `# Kiosk settings
+rotate = 90
+font="/usr/share/fonts/kiosk.ttf"
+host='0.0.0.0'
+
+status-top=true
+`
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := ParseConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]string{
+		"rotate":     "90",
+		"font":       "/usr/share/fonts/kiosk.ttf",
+		"host":       "0.0.0.0",
+		"status-top": "true",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("rotate 90\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseConfig(path); err == nil {
+		t.Errorf("missing = did not return an error")
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	saved := keepConfig(t)
+	savedFlags := flag.CommandLine
+	defer func() { flag.CommandLine = savedFlags }()
+
+	var matrix = []struct {
+		args   []string
+		env    map[string]string
+		config string
+		name   string
+		value  string
+		err    bool
+	}{
+		// The cmdline takes precedence over the environment, which takes precedence over the config file
+		{nil, nil, "fps = 10\n", "fps", "10", false},
+		{nil, map[string]string{"SDL2_LIFE_FPS": "20"}, "fps = 10\n", "fps", "20", false},
+		{[]string{"-fps", "30"}, map[string]string{"SDL2_LIFE_FPS": "20"}, "fps = 10\n", "fps", "30", false},
+		{[]string{"-fps", "30"}, nil, "fps = 10\n", "fps", "30", false},
+		// Dashes in the flag names are underscores in the environment
+		{nil, map[string]string{"SDL2_LIFE_STATUS_TOP": "true"}, "", "status-top", "true", false},
+		{nil, map[string]string{"SDL2_LIFE_TRAIL_LENGTH": "5"}, "trail-length = 3\n", "trail-length", "5", false},
+		// Unknown settings and bad values are errors
+		{nil, nil, "speed = 10\n", "", "", true},
+		{nil, nil, "config = other\n", "", "", true},
+		{nil, nil, "fps = fast\n", "", "", true},
+		{nil, map[string]string{"SDL2_LIFE_FPS": "fast"}, "", "", "", true},
+	}
+
+	for _, tt := range matrix {
+		cfg = saved
+		cfg.ConfigFile = filepath.Join(t.TempDir(), "config")
+		if err := ioutil.WriteFile(cfg.ConfigFile, []byte(tt.config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for name, value := range tt.env {
+			os.Setenv(name, value)
+		}
+		flag.CommandLine = flag.NewFlagSet("sdl2-life", flag.ContinueOnError)
+		defineFlags()
+		if err := flag.CommandLine.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+
+		err := loadDefaults()
+		for name := range tt.env {
+			os.Unsetenv(name)
+		}
+		if (err != nil) != tt.err {
+			t.Errorf("%v %v %q: expected error %v, got %v", tt.args, tt.env, tt.config, tt.err, err)
+			continue
+		}
+		if err == nil {
+			if value := flag.Lookup(tt.name).Value.String(); value != tt.value {
+				t.Errorf("%v %v %q: expected %s = %s, got %s", tt.args, tt.env, tt.config, tt.name, tt.value, value)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+//go:build go1.18
+// +build go1.18
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// addExamples adds the example patterns matching glob to the fuzzing corpus
+func addExamples(f *testing.F, glob string) {
+	files, err := filepath.Glob(filepath.Join("examples", glob))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(data))
+	}
+}
+
+func FuzzParseRLE(f *testing.F) {
+	addExamples(f, "*.rle")
+	f.Add("#CXRLE Pos=-1,-1 Gen=4\nx = 3, y = 3, rule = B3/S23\nbo$2bo$3o!")
+	f.Add("\n#P 1 1\nx = 2, y = 2\n65536o$65536$!")
+	f.Fuzz(func(t *testing.T, data string) {
+		g := newTestGame(16, 16)
+		g.ParseRLE(strings.Split(data, "\n"), 0, 0)
+	})
+}
+
+func FuzzParseLife105(f *testing.F) {
+	addExamples(f, "*.life")
+	f.Add("#Life 1.05\n#R 23/3\n#P -1 -1\n.*\n#P 4 4\n***")
+	f.Fuzz(func(t *testing.T, data string) {
+		g := newTestGame(16, 16)
+		g.ParseLife105(strings.Split(data, "\n"), 0, 0)
+	})
+}
+
+func FuzzParsePlaintext(f *testing.F) {
+	addExamples(f, "*.cells")
+	f.Fuzz(func(t *testing.T, data string) {
+		g := newTestGame(16, 16)
+		g.ParsePlaintext(strings.Split(data, "\n"), 0, 0)
+	})
+}
+
+func FuzzLoadPattern(f *testing.F) {
+	addExamples(f, "*")
+	f.Fuzz(func(t *testing.T, data string) {
+		g := newTestGame(16, 16)
+		g.LoadPattern(strings.Split(data, "\n"), 0, 0)
+	})
+}
+
+func FuzzParseRulestring(f *testing.F) {
+	for _, rule := range []string{"B3/S23", "B36/S23", "B/S", "B2/S", "B3/S012345678", "S23/b3", "23/3", "B2/S34H", "R5,C0,M1,S34..58,B34..45,NM", lifeMap} {
+		f.Add(rule)
+	}
+	f.Fuzz(func(t *testing.T, rule string) {
+		r, err := ParseRulestring(rule)
+		if err == nil && (r.Birth == nil || r.StayAlive == nil) {
+			t.Errorf("%q: no error and no rule", rule)
+		}
+		if err != nil {
+			return
+		}
+		// The canonical form parses to the same rule
+		c, err := ParseRulestring(r.Rulestring)
+		if err != nil || c.Rulestring != r.Rulestring {
+			t.Errorf("%q: canonical %q parsed as %q: %v", rule, r.Rulestring, c.Rulestring, err)
+		}
+	})
+}
+
+func FuzzParseRuleFile(f *testing.F) {
+	f.Add("@RULE Diamond\n@NEIGHBORHOOD\n..*..\n.***.\n**.**\n.***.\n..*..\n@BIRTH 4 5\n@SURVIVAL 3 4 5 6")
+	f.Add("@RULE Cross\n@NEIGHBORHOOD 0,-1 -1,0 1,0 0,1\n@BIRTH 1,3\n@SURVIVAL 1 2")
+	f.Add("@RULE Table\n@TABLE\nn_states:3\nneighborhood:vonNeumann\nsymmetries:rotate4\nvar a={1,2}\n0,a,0,a,0,a\n1,0,0,0,0,2\n@COLORS\n1 255 0 0")
+	f.Fuzz(func(t *testing.T, data string) {
+		ParseRuleFile(strings.Split(data, "\n"))
+	})
+}
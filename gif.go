@@ -0,0 +1,252 @@
+// sdl2-life
+// by Brian C. Lane <bcl@brianlane.com>
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"log"
+	"os"
+)
+
+// maxGIFDelay is the longest a GIF frame can be shown for, in 100ths of a second
+const maxGIFDelay = 0xffff
+
+// GIFRecorder writes frames of the world to an animated GIF as they are added
+// Only the last frame is kept in memory, so it can record for as long as needed.
+type GIFRecorder struct {
+	path     string
+	cellSize int
+	delay    int
+	palette  color.Palette
+	meta     []MetaField // Pattern information, written as a comment
+	f        *os.File
+	w        *bufio.Writer
+	last     *image.Paletted // Last full frame, used to find the changes in the next one
+	pending  []byte          // Last encoded frame, written when its delay is known
+	wait     int             // Delay of the pending frame
+	frames   int             // Number of frames written
+}
+
+// NewGIFRecorder creates the file and returns a recorder that writes to it
+// The palette is built from the game's colors and its gradient.
+func NewGIFRecorder(g *LifeGame, path string, cellSize, delay int) (*GIFRecorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &GIFRecorder{
+		path:     path,
+		cellSize: cellSize,
+		delay:    delay,
+		palette:  g.Palette(256),
+		meta:     g.Metadata(),
+		f:        f,
+		w:        bufio.NewWriter(f),
+	}, nil
+}
+
+// Palette returns up to size colors for the background, foreground, trail, rule and gradient
+// The colors of a multi-state rule's states, or of the species, come before the gradient so
+// that they are not mapped to the nearest gradient color. The gradient is sampled evenly if it
+// has too many colors to fit.
+func (g *LifeGame) Palette(size int) color.Palette {
+	var palette color.Palette
+	seen := make(map[RGBAColor]bool)
+	add := func(c RGBAColor) {
+		if !seen[c] && len(palette) < size {
+			seen[c] = true
+			palette = append(palette, c.Color())
+		}
+	}
+	add(g.bg)
+	add(g.fg)
+	add(g.trail)
+	if g.rule.Table != nil {
+		for s := 1; s < g.rule.Table.States; s++ {
+			add(g.StateColor(uint8(s)))
+		}
+	}
+	for s := 1; s <= g.rule.Species; s++ {
+		add(g.SpeciesColor(uint8(s)))
+	}
+
+	points := len(g.gradient.points)
+	remaining := size - len(palette)
+	for i := 0; i < remaining && i < points; i++ {
+		if points <= remaining {
+			add(g.gradient.points[i])
+		} else if remaining == 1 {
+			// Only room for one, use the oldest color
+			add(g.gradient.points[points-1])
+		} else {
+			add(g.gradient.points[i*(points-1)/(remaining-1)])
+		}
+	}
+	return palette
+}
+
+// AddFrame adds the current state of the world to the animation
+// Only the rectangle that changed since the last frame is stored, if nothing
+// changed the last frame is displayed for longer.
+func (r *GIFRecorder) AddFrame(g *LifeGame) error {
+	img := g.RenderImage(r.cellSize, cfg.Border, "display")
+	frame := image.NewPaletted(img.Bounds(), r.palette)
+	draw.Draw(frame, frame.Bounds(), img, image.Point{}, draw.Src)
+
+	if r.last == nil {
+		r.last = frame
+		return r.addImage(frame, true)
+	}
+
+	changed := changedRect(r.last, frame)
+	if changed.Empty() {
+		r.wait += r.delay
+		if r.wait > maxGIFDelay {
+			r.wait = maxGIFDelay
+		}
+		return nil
+	}
+	r.last = frame
+	return r.addImage(frame.SubImage(changed).(*image.Paletted), false)
+}
+
+// addImage encodes the image, and writes the one before it now that its delay is known
+// The first image also writes the GIF header, the loop extension, and the metadata comment.
+func (r *GIFRecorder) addImage(img *image.Paletted, first bool) error {
+	var buf bytes.Buffer
+	bounds := r.last.Bounds()
+	anim := gif.GIF{
+		Image:  []*image.Paletted{img},
+		Delay:  []int{0},
+		Config: image.Config{ColorModel: r.palette, Width: bounds.Dx(), Height: bounds.Dy()},
+	}
+	if err := gif.EncodeAll(&buf, &anim); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+
+	// The 6 byte header and 7 byte logical screen descriptor are followed by the color table
+	start := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		start += 3 << (flags&0x07 + 1)
+	}
+	if first {
+		r.w.Write(data[:start])
+		// Loop forever
+		r.w.Write([]byte{0x21, 0xff, 0x0b})
+		r.w.WriteString("NETSCAPE2.0")
+		r.w.Write([]byte{0x03, 0x01, 0x00, 0x00, 0x00})
+		r.w.Write(gifComment(r.meta))
+	}
+
+	if err := r.writePending(); err != nil {
+		return err
+	}
+	// Only keep the image descriptor and data, without any extensions or the trailer
+	i := start
+	for i < len(data) && data[i] == 0x21 {
+		for i += 2; i < len(data) && data[i] != 0; i += int(data[i]) + 1 {
+		}
+		i++
+	}
+	r.pending = append(r.pending[:0], data[i:len(data)-1]...)
+	r.wait = r.delay
+	return nil
+}
+
+// writePending writes the pending image with a graphic control extension for its delay
+func (r *GIFRecorder) writePending() error {
+	if len(r.pending) == 0 {
+		return nil
+	}
+	r.w.Write([]byte{0x21, 0xf9, 0x04, gif.DisposalNone << 2, byte(r.wait), byte(r.wait >> 8), 0x00, 0x00})
+	_, err := r.w.Write(r.pending)
+	r.pending = r.pending[:0]
+	r.frames++
+	return err
+}
+
+// changedRect returns the smallest rectangle containing all the pixels that differ between a and b
+func changedRect(a, b *image.Paletted) image.Rectangle {
+	var changed image.Rectangle
+	bounds := b.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if a.ColorIndexAt(x, y) != b.ColorIndexAt(x, y) {
+				changed = changed.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return changed
+}
+
+// Close writes the last frame and the end of the GIF, and closes the file
+func (r *GIFRecorder) Close() error {
+	err := r.writePending()
+	if err == nil {
+		err = r.w.WriteByte(0x3b)
+	}
+	if err == nil {
+		err = r.w.Flush()
+	}
+	if err != nil {
+		r.f.Close()
+		return err
+	}
+	return r.f.Close()
+}
+
+// StartRecording starts recording the world to an animated GIF
+func (g *LifeGame) StartRecording(path string) {
+	cellSize := cfg.GIFCellSize
+	if cellSize == 0 {
+		cellSize = cfg.CellSize
+	}
+	r, err := NewGIFRecorder(g, path, cellSize, cfg.GIFDelay)
+	if err != nil {
+		log.Printf("Failed to record GIF: %s\n", err)
+		return
+	}
+	log.Printf("Recording to %s\n", path)
+	g.gif = r
+	if err := g.gif.AddFrame(g); err != nil {
+		log.Printf("Failed to write GIF, stopping: %s\n", err)
+		g.StopRecording()
+	}
+}
+
+// StopRecording writes the animated GIF, if one is being recorded
+func (g *LifeGame) StopRecording() {
+	if g.gif == nil {
+		return
+	}
+	if err := g.gif.Close(); err != nil {
+		log.Printf("Failed to write GIF: %s\n", err)
+	} else {
+		log.Printf("Saved %s with %d frames\n", g.gif.path, g.gif.frames)
+	}
+	g.gif = nil
+}
+
+// RecordFrame handles starting, stopping, and adding frames for -record-gif
+func (g *LifeGame) RecordFrame() {
+	if g.gif != nil {
+		if err := g.gif.AddFrame(g); err != nil {
+			log.Printf("Failed to write GIF, stopping: %s\n", err)
+			g.StopRecording()
+			g.gifDone = true
+			return
+		}
+		if cfg.RecordEnd > 0 && g.generation >= cfg.RecordEnd && g.gif.path == cfg.RecordGIF {
+			g.StopRecording()
+			g.gifDone = true
+		}
+	} else if len(cfg.RecordGIF) > 0 && !g.gifDone && g.generation == cfg.RecordStart {
+		g.StartRecording(cfg.RecordGIF)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"image"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGIFRecorder(t *testing.T) {
+	g := newTestGame(5, 5)
+	g.trail = RGBAColor{128, 128, 128, 255}
+
+	path := filepath.Join(t.TempDir(), "test.gif")
+	r, err := NewGIFRecorder(g, path, 2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Blinker, then the same frame again, then the other phase
+	for _, x := range []int{1, 2, 3} {
+		g.SetCellState(x, 2, true)
+	}
+	r.AddFrame(g)
+	r.AddFrame(g)
+	g.SetCellState(1, 2, false)
+	g.SetCellState(3, 2, false)
+	g.SetCellState(2, 1, true)
+	g.SetCellState(2, 3, true)
+	r.AddFrame(g)
+	// Frames are written as soon as the next one changes, only the last one is waiting
+	if r.frames != 1 {
+		t.Errorf("expected 1 frame to have been written, got %d", r.frames)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close failed: %s", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	anim, err := gif.DecodeAll(f)
+	if err != nil {
+		t.Fatalf("DecodeAll failed: %s", err)
+	}
+
+	if len(anim.Image) != 2 {
+		t.Fatalf("expected 2 frames, got %d", len(anim.Image))
+	}
+	if anim.Delay[0] != 10 || anim.Delay[1] != 5 {
+		t.Errorf("expected delays [10 5], got %v", anim.Delay)
+	}
+	if anim.Image[0].Bounds() != image.Rect(0, 0, 10, 10) {
+		t.Errorf("wrong first frame bounds: %v", anim.Image[0].Bounds())
+	}
+	// Only the changed cells are stored in the second frame
+	if anim.Image[1].Bounds() != image.Rect(2, 2, 8, 8) {
+		t.Errorf("wrong second frame bounds: %v", anim.Image[1].Bounds())
+	}
+}
+
+func TestPaletteRuleColors(t *testing.T) {
+	keepConfig(t)
+
+	for _, name := range []string{"WireWorld", "QuadLife"} {
+		g := newTestGame(4, 4)
+		rule, err := LookupRule(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.SetRule(rule)
+
+		var colors []RGBAColor
+		if rule.Table != nil {
+			for s := 1; s < rule.Table.States; s++ {
+				colors = append(colors, g.StateColor(uint8(s)))
+			}
+		}
+		for s := 1; s <= rule.Species; s++ {
+			colors = append(colors, g.SpeciesColor(uint8(s)))
+		}
+		palette := g.Palette(256)
+		for _, c := range colors {
+			if palette.Convert(c.Color()) != c.Color() {
+				t.Errorf("%s: %v is not in the palette", name, c)
+			}
+		}
+	}
+}
+
+func TestPaletteFull(t *testing.T) {
+	// Rule colors that leave room for 1 or none of the gradient colors
+	for _, states := range []int{252, 253, 254, 256} {
+		g := newTestGame(4, 4)
+		table := &RuleTable{States: states, Colors: make(map[uint8]RGBAColor)}
+		for s := 1; s < states; s++ {
+			table.Colors[uint8(s)] = RGBAColor{uint8(s), 1, 2, 255}
+		}
+		g.rule.Table = table
+
+		palette := g.Palette(256)
+		if len(palette) != 256 {
+			t.Errorf("%d states: expected 256 colors, got %d", states, len(palette))
+		}
+	}
+}
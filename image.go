@@ -0,0 +1,84 @@
+// sdl2-life
+// by Brian C. Lane <bcl@brianlane.com>
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+)
+
+// Color returns the color as a color.RGBA for use with the image package
+func (c RGBAColor) Color() color.RGBA {
+	return color.RGBA{c.r, c.g, c.b, c.a}
+}
+
+// isImageScheme returns true if the scheme is one of the supported image color schemes
+func isImageScheme(scheme string) bool {
+	switch scheme {
+	case "display", "mono", "age", "heat":
+		return true
+	}
+	return false
+}
+
+// ImageColor returns the color to use for a cell in an image
+// The display scheme uses the same colors as the window, the others select one
+// coloring method no matter what is currently being displayed.
+// It returns false if the cell should be left as background
+func (g *LifeGame) ImageColor(c *Cell, scheme string) (RGBAColor, bool) {
+	switch scheme {
+	case "mono":
+		return g.fg, c.alive
+	case "age":
+		if c.alive {
+			return g.GradientColor(c.age), true
+		}
+		return g.bg, false
+	case "heat":
+		if c.heat > heatThreshold {
+			return g.HeatColor(c.heat), true
+		}
+		return g.bg, false
+	}
+	return g.CellColor(c)
+}
+
+// RenderImage draws the world into an image
+// Each cell is cellSize pixels square, with a 1 pixel background border around it
+// when border is true. Hexagonal worlds are sheared the same way as the window.
+func (g *LifeGame) RenderImage(cellSize int, border bool, scheme string) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, g.columns*cellSize, g.rows*cellSize))
+	draw.Draw(img, img.Bounds(), &image.Uniform{g.bg.Color()}, image.Point{}, draw.Src)
+
+	for y := range g.cells {
+		for _, c := range g.cells[y] {
+			color, ok := g.ImageColor(c, scheme)
+			if !ok {
+				continue
+			}
+			for _, r := range g.CellRects(*c, cellSize, border) {
+				draw.Draw(img, r, &image.Uniform{color.Color()}, image.Point{}, draw.Src)
+			}
+		}
+	}
+	return img
+}
+
+// SavePNG writes the world to a PNG file using the -png-* settings
+func (g *LifeGame) SavePNG(path string) error {
+	cellSize := cfg.PNGCellSize
+	if cellSize == 0 {
+		cellSize = cfg.CellSize
+	}
+	img := g.RenderImage(cellSize, cfg.PNGBorder, cfg.PNGScheme)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, pngText(buf.Bytes(), g.Metadata()), 0644)
+}
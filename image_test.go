@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// newTestGame returns a game with an empty world that doesn't need SDL
+func newTestGame(columns, rows int) *LifeGame {
+	g := &LifeGame{columns: columns, rows: rows}
+	g.bg = RGBAColor{0, 0, 0, 255}
+	g.fg = RGBAColor{255, 255, 255, 255}
+	g.gradient, _ = NewLinearGradient([]RGBAColor{{0, 0, 255, 255}, {255, 0, 0, 255}}, 10)
+	g.ClearCells()
+	return g
+}
+
+// keepConfig restores cfg when the test is done, so that the test can change it
+// It returns the settings from before the test.
+func keepConfig(t *testing.T) cmdlineArgs {
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	return saved
+}
+
+// patternGame returns a game with the pattern loaded, using its rule
+func patternGame(t *testing.T, columns, rows int, pattern string) *LifeGame {
+	g := newTestGame(columns, rows)
+	info, err := g.LoadPattern(strings.Split(pattern, "\n"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.ApplyPatternRule(info); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// useRule switches a test game to the rule, and returns it
+func useRule(t *testing.T, g *LifeGame, rule string) *LifeGame {
+	if err := g.UseRule(rule); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestRenderImage(t *testing.T) {
+	g := newTestGame(4, 3)
+	g.SetCellState(1, 2, true)
+	g.cells[2][1].age = 9
+
+	var matrix = []struct {
+		scheme string
+		border bool
+		x, y   int
+		color  RGBAColor
+	}{
+		{"mono", false, 5, 10, g.fg},
+		{"mono", false, 4, 8, g.fg},
+		{"mono", true, 4, 8, g.bg},
+		{"mono", true, 5, 9, g.fg},
+		{"mono", false, 0, 0, g.bg},
+		{"age", false, 5, 10, RGBAColor{255, 0, 0, 255}},
+		{"heat", false, 5, 10, g.bg},
+	}
+
+	for _, tt := range matrix {
+		img := g.RenderImage(4, tt.border, tt.scheme)
+		if img.Bounds().Dx() != 16 || img.Bounds().Dy() != 12 {
+			t.Fatalf("wrong image size: %v", img.Bounds())
+		}
+		if c := img.RGBAAt(tt.x, tt.y); c != tt.color.Color() {
+			t.Errorf("%s border=%v: expected %v at %d,%d, got %v", tt.scheme, tt.border, tt.color, tt.x, tt.y, c)
+		}
+	}
+}
+
+func TestRenderImageGolden(t *testing.T) {
+	keepConfig(t)
+	cfg.HexShear = true
+
+	var matrix = []struct {
+		name   string
+		border bool
+	}{
+		{"export-hex.png", false},
+		{"export-hex-border.png", true},
+	}
+
+	for _, tt := range matrix {
+		g := useRule(t, newTestGame(16, 9), "B2/S34H")
+
+		// A glider in the top left corner, and cells on the right edge that wrap around when sheared
+		for _, xy := range [][2]int{{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2}, {15, 0}, {15, 4}, {15, 7}, {15, 8}} {
+			g.SetCellState(xy[0], xy[1], true)
+		}
+		img := g.RenderImage(4, tt.border, "mono")
+
+		if *update {
+			if err := writeGolden(tt.name, img); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		expected, err := readGolden(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := compareImages(expected, img); err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+		}
+	}
+}
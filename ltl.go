@@ -0,0 +1,283 @@
+// sdl2-life
+// by Brian C. Lane <bcl@brianlane.com>
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxLtLRange is the largest range for a Larger than Life rule
+const maxLtLRange = 50
+
+// ParseLtL parses a Larger than Life rule like Bosco's Rule, R5,C0,M1,S34..58,B34..45,NM
+//
+//	R is the range of the neighborhood, from 1 to 50
+//	C is the number of states, only 0 or 2 are supported
+//	M is 1 if the cell itself is counted, or 0 if it is not
+//	S and B are the neighbor counts to stay alive and be born, as a list of a..b ranges or numbers
+//	N is the neighborhood, M for Moore (a square), N for von Neumann (a diamond) or C for circular
+func ParseLtL(rule string) (Rule, error) {
+	r := Rule{Birth: make(map[int]bool), StayAlive: make(map[int]bool), Shape: 'M'}
+	var key byte
+	var haveS, haveB bool
+	counts := make(map[byte][][2]int)
+	for _, field := range strings.Split(rule, ",") {
+		field = strings.TrimSpace(field)
+		if len(field) == 0 {
+			continue
+		}
+		value := field
+		if field[0] < '0' || field[0] > '9' {
+			key, value = field[0], field[1:]
+		} else if key != 'S' && key != 'B' {
+			return r, fmt.Errorf("Unexpected %q in Larger than Life rule", field)
+		}
+
+		var err error
+		switch key {
+		case 'R':
+			if r.Range, err = strconv.Atoi(value); err != nil || r.Range < 1 || r.Range > maxLtLRange {
+				return r, fmt.Errorf("Range must be from 1 to %d: %s", maxLtLRange, field)
+			}
+		case 'C':
+			if states, err := strconv.Atoi(value); err != nil || (states != 0 && states != 2) {
+				return r, fmt.Errorf("Only 2 states (C0 or C2) are supported: %s", field)
+			}
+		case 'M':
+			if value != "0" && value != "1" {
+				return r, fmt.Errorf("Middle must be M0 or M1: %s", field)
+			}
+			r.Middle = value == "1"
+		case 'N':
+			if value != "M" && value != "N" && value != "C" {
+				return r, fmt.Errorf("Neighborhood must be NM, NN, or NC: %s", field)
+			}
+			r.Shape = value[0]
+		case 'S', 'B':
+			if key == 'S' {
+				haveS = true
+			} else {
+				haveB = true
+			}
+			if len(value) == 0 {
+				continue
+			}
+			lo, hi, err := parseLtLRange(value)
+			if err != nil {
+				return r, err
+			}
+			counts[key] = append(counts[key], [2]int{lo, hi})
+		default:
+			return r, fmt.Errorf("Unknown %q in Larger than Life rule", field)
+		}
+	}
+	if r.Range == 0 || !haveS || !haveB {
+		return r, fmt.Errorf("Larger than Life rules need R, S, and B, eg. R5,C0,M1,S34..58,B34..45,NM")
+	}
+
+	size := len(ltlOffsets(r.Range, r.Shape))
+	if r.Middle {
+		size++
+	}
+	for key, ranges := range counts {
+		for _, c := range ranges {
+			if c[1] > size {
+				return r, fmt.Errorf("Count %d is larger than the neighborhood size of %d", c[1], size)
+			}
+			for n := c[0]; n <= c[1]; n++ {
+				if key == 'S' {
+					r.StayAlive[n] = true
+				} else {
+					r.Birth[n] = true
+				}
+			}
+		}
+	}
+	middle := 0
+	if r.Middle {
+		middle = 1
+	}
+	r.Rulestring = fmt.Sprintf("R%d,C0,M%d,S%s,B%s,N%c", r.Range, middle, formatLtLCounts(r.StayAlive, size),
+		formatLtLCounts(r.Birth, size), r.Shape)
+	return r, nil
+}
+
+// formatLtLCounts returns the counts as a list of a..b ranges and single numbers
+func formatLtLCounts(counts map[int]bool, size int) string {
+	var ranges []string
+	for n := 0; n <= size; n++ {
+		if !counts[n] {
+			continue
+		}
+		lo := n
+		for n < size && counts[n+1] {
+			n++
+		}
+		if n == lo {
+			ranges = append(ranges, strconv.Itoa(n))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d..%d", lo, n))
+		}
+	}
+	return strings.Join(ranges, ",")
+}
+
+// parseLtLRange parses a..b or a single count
+func parseLtLRange(s string) (int, int, error) {
+	fields := strings.Split(s, "..")
+	if len(fields) > 2 {
+		return 0, 0, fmt.Errorf("Cannot parse range %s", s)
+	}
+	lo, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("Cannot parse range %s: %s", s, err)
+	}
+	hi := lo
+	if len(fields) == 2 {
+		if hi, err = strconv.Atoi(fields[1]); err != nil {
+			return 0, 0, fmt.Errorf("Cannot parse range %s: %s", s, err)
+		}
+	}
+	if lo < 0 || hi < lo {
+		return 0, 0, fmt.Errorf("Range %s must be low..high", s)
+	}
+	return lo, hi, nil
+}
+
+// ltlWidths returns how far the neighborhood extends to each side, for each row from -r to r
+func ltlWidths(r int, shape byte) []int {
+	widths := make([]int, 2*r+1)
+	for dy := -r; dy <= r; dy++ {
+		switch shape {
+		case 'N':
+			widths[dy+r] = r - abs(dy)
+		case 'C':
+			// Cells with centers within r+0.5 of the cell
+			w := 0
+			for (w+1)*(w+1)+dy*dy <= r*r+r {
+				w++
+			}
+			widths[dy+r] = w
+		default:
+			widths[dy+r] = r
+		}
+	}
+	return widths
+}
+
+// ltlOffsets returns the neighborhood, without the cell itself
+func ltlOffsets(r int, shape byte) Neighborhood {
+	var n Neighborhood
+	for i, w := range ltlWidths(r, shape) {
+		for dx := -w; dx <= w; dx++ {
+			if dx != 0 || i != r {
+				n = append(n, [2]int{dx, i - r})
+			}
+		}
+	}
+	return n
+}
+
+// abs returns the absolute value of an int
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// rangeSum returns the sum from x0 to x1 inclusive, using the prefix sums p of a row of n
+// values. It wraps around the edges, more than once if the range is wider than the row.
+func rangeSum(p []int, x0, x1, n int) int {
+	length := x1 - x0 + 1
+	sum := (length / n) * p[n]
+	length %= n
+	start := (x0%n + n) % n
+	end := start + length
+	if end <= n {
+		sum += p[end] - p[start]
+	} else {
+		sum += p[n] - p[start] + p[end-n]
+	}
+	return sum
+}
+
+// CountRange counts the live neighbors, and the sum of their ages, for every cell
+// It is used for Larger than Life rules instead of checking each neighbor of each cell.
+// Each row is turned into prefix sums, so any part of a row can be added up in one step.
+// The Moore neighborhood is a square, so the row sums are added up the same way down
+// each column. Other shapes add up each row of the neighborhood.
+func (g *LifeGame) CountRange() {
+	r := g.rule.Range
+	rowLive := make([][]int, g.rows)
+	rowAge := make([][]int, g.rows)
+	for y := range g.cells {
+		rowLive[y] = make([]int, g.columns+1)
+		rowAge[y] = make([]int, g.columns+1)
+		for x, c := range g.cells[y] {
+			rowLive[y][x+1] = rowLive[y][x]
+			rowAge[y][x+1] = rowAge[y][x]
+			if c.alive {
+				rowLive[y][x+1]++
+				rowAge[y][x+1] += c.age
+			}
+		}
+	}
+
+	if len(g.counts) != g.rows || (g.rows > 0 && len(g.counts[0]) != g.columns) {
+		g.counts = make([][]int, g.rows)
+		g.ageSums = make([][]int, g.rows)
+		for y := range g.counts {
+			g.counts[y] = make([]int, g.columns)
+			g.ageSums[y] = make([]int, g.columns)
+		}
+	}
+
+	if g.rule.Shape == 'M' {
+		// Prefix sums down each column of the row sums
+		colLive := make([][]int, g.columns)
+		colAge := make([][]int, g.columns)
+		for x := 0; x < g.columns; x++ {
+			colLive[x] = make([]int, g.rows+1)
+			colAge[x] = make([]int, g.rows+1)
+			for y := 0; y < g.rows; y++ {
+				colLive[x][y+1] = colLive[x][y] + rangeSum(rowLive[y], x-r, x+r, g.columns)
+				colAge[x][y+1] = colAge[x][y] + rangeSum(rowAge[y], x-r, x+r, g.columns)
+			}
+		}
+		for y := 0; y < g.rows; y++ {
+			for x := 0; x < g.columns; x++ {
+				g.counts[y][x] = rangeSum(colLive[x], y-r, y+r, g.rows)
+				g.ageSums[y][x] = rangeSum(colAge[x], y-r, y+r, g.rows)
+			}
+		}
+	} else {
+		widths := ltlWidths(r, g.rule.Shape)
+		for y := 0; y < g.rows; y++ {
+			for x := 0; x < g.columns; x++ {
+				var live, age int
+				for i, w := range widths {
+					row := ((y+i-r)%g.rows + g.rows) % g.rows
+					live += rangeSum(rowLive[row], x-w, x+w, g.columns)
+					age += rangeSum(rowAge[row], x-w, x+w, g.columns)
+				}
+				g.counts[y][x] = live
+				g.ageSums[y][x] = age
+			}
+		}
+	}
+
+	// The sums include the cell itself, remove it unless the rule counts it
+	if !g.rule.Middle {
+		for y := range g.cells {
+			for x, c := range g.cells[y] {
+				if c.alive {
+					g.counts[y][x]--
+					g.ageSums[y][x] -= c.age
+				}
+			}
+		}
+	}
+}
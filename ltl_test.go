@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestParseLtL(t *testing.T) {
+	r, err := ParseRulestring("R5,C0,M1,S34..58,B34..45,NM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Range != 5 || !r.Middle || r.Shape != 'M' {
+		t.Errorf("wrong range, middle, or shape: %#v", r)
+	}
+	if !r.StayAlive[34] || !r.StayAlive[58] || r.StayAlive[33] || r.StayAlive[59] || len(r.StayAlive) != 25 {
+		t.Errorf("wrong survival counts: %v", r.StayAlive)
+	}
+	if !r.Birth[34] || !r.Birth[45] || len(r.Birth) != 12 {
+		t.Errorf("wrong birth counts: %v", r.Birth)
+	}
+
+	r, err = ParseRulestring("R2,C2,M0,S1,3..4,B2,NN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Shape != 'N' || !r.StayAlive[1] || r.StayAlive[2] || !r.StayAlive[4] || !r.Birth[2] {
+		t.Errorf("R2,C2,M0,S1,3..4,B2,NN parsed as %#v", r)
+	}
+
+	for _, rule := range []string{
+		"R0,C0,M0,S2..3,B3,NM",
+		"R51,C0,M0,S2..3,B3,NM",
+		"R1,C3,M0,S2..3,B3,NM",
+		"R1,C0,M2,S2..3,B3,NM",
+		"R1,C0,M0,S2..3,NM",
+		"R1,C0,M0,S2..9,B3,NM",
+		"R1,C0,M0,S3..2,B3,NM",
+		"R1,C0,M0,S2..3,B3,NX",
+		"R1,C0,M0,S2..3,B3,NM,X1",
+	} {
+		if _, err := ParseRulestring(rule); err == nil {
+			t.Errorf("%s: did not return an error", rule)
+		}
+	}
+}
+
+// randomGame returns a game with a random world
+func randomGame(columns, rows int, seed int64) *LifeGame {
+	g := newTestGame(columns, rows)
+	rnd := rand.New(rand.NewSource(seed))
+	for y := 0; y < rows; y++ {
+		for x := 0; x < columns; x++ {
+			g.SetCellState(x, y, rnd.Float64() < 0.4)
+			g.cells[y][x].age = rnd.Intn(10)
+		}
+	}
+	return g
+}
+
+func TestCountRange(t *testing.T) {
+	for _, rule := range []string{
+		"R2,C0,M0,S2..3,B3,NM",
+		"R2,C0,M1,S2..3,B3,NN",
+		"R3,C0,M0,S2..3,B3,NC",
+		// Wider than the world, so it wraps around more than once
+		"R7,C0,M0,S2..3,B3,NM",
+		"R7,C0,M1,S2..3,B3,NC",
+	} {
+		g := randomGame(13, 11, 1)
+		var err error
+		if g.rule, err = ParseRulestring(rule); err != nil {
+			t.Fatal(err)
+		}
+		g.CountRange()
+
+		neighborhood := ltlOffsets(g.rule.Range, g.rule.Shape)
+		if g.rule.Middle {
+			neighborhood = append(neighborhood, [2]int{0, 0})
+		}
+		for y := 0; y < g.rows; y++ {
+			for x := 0; x < g.columns; x++ {
+				var live, age int
+				for _, offset := range neighborhood {
+					c := g.cells[((y+offset[1])%g.rows+g.rows)%g.rows][((x+offset[0])%g.columns+g.columns)%g.columns]
+					if c.alive {
+						live++
+						age += c.age
+					}
+				}
+				if g.counts[y][x] != live || g.ageSums[y][x] != age {
+					t.Fatalf("%s: cell %d, %d expected %d and %d, got %d and %d", rule, x, y, live, age, g.counts[y][x], g.ageSums[y][x])
+				}
+			}
+		}
+	}
+}
+
+func TestLtLLife(t *testing.T) {
+	// Range 1 Moore is the same as Conway's Life
+	life := randomGame(20, 15, 2)
+	ltl := randomGame(20, 15, 2)
+	rule, err := ParseRulestring("B3/S23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	life.SetRule(rule)
+	if rule, err = ParseRulestring("R1,C0,M0,S2..3,B3..3,NM"); err != nil {
+		t.Fatal(err)
+	}
+	ltl.SetRule(rule)
+	for i := 0; i < 10; i++ {
+		life.NextFrame()
+		ltl.NextFrame()
+	}
+	for y := range life.cells {
+		for x := range life.cells[y] {
+			if life.cells[y][x].alive != ltl.cells[y][x].alive {
+				t.Fatalf("cell %d, %d is different", x, y)
+			}
+		}
+	}
+}
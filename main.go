@@ -6,6 +6,8 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"image"
+	"io"
 	"log"
 	"math"
 	"math/rand"
@@ -22,6 +24,14 @@ import (
 
 const (
 	threshold = 0.15
+	// heatThreshold is the minimum activity needed to show a cell on the heat map
+	heatThreshold = 0.05
+	// maxPatternSize is the largest RLE width, height, or run count
+	maxPatternSize = 1 << 16
+	// maxPatternBytes is the largest pattern accepted by the server
+	maxPatternBytes = 1 << 20
+	// maxRuleLabel is the longest rulestring shown in the status bar
+	maxRuleLabel = 32
 	// LinearGradient cmdline selection
 	LinearGradient = 0
 	// PolylinearGradient cmdline selection
@@ -30,33 +40,73 @@ const (
 	BezierGradient = 2
 )
 
+// rulestringRegex matches B/S rules with the B and S in either order, and an optional /
+var rulestringRegex = regexp.MustCompile(`^(?:B(\d*)/?S(\d*)|S(\d*)/?B(\d*))$`)
+
+// survivalBirthRegex matches the S/B rules used by Life 1.05 files, without any letters
+var survivalBirthRegex = regexp.MustCompile(`^(\d*)/(\d*)$`)
+
 // RLE header with variable spacing and optional rules
 // Matches it with or without rule at the end, and with 0 or more spaces between elements.
 var rleHeaderRegex = regexp.MustCompile(`x\s*=\s*(\d+)\s*,\s*y\s*=\s*(\d+)(?:\s*,\s*rule\s*=\s*(.*))*`)
 
 /* commandline flags */
 type cmdlineArgs struct {
-	Width       int    // Width of window in pixels
-	Height      int    // Height of window in pixels
-	CellSize    int    // Cell size in pixels (square)
-	Seed        int64  // Seed for PRNG
-	Border      bool   // Border around cells
-	Font        string // Path to TTF to use for status bar
-	FontSize    int    // Size of font in points
-	Rule        string // Rulestring to use
-	Fps         int    // Frames per Second
-	PatternFile string // File with initial pattern
-	Pause       bool   // Start the game paused
-	Empty       bool   // Start with empty world
-	Color       bool   // Color the cells based on age
-	Colors      string // Comma separated color hex triplets
-	Gradient    int    // Gradient algorithm to use
-	MaxAge      int    // Maximum age for gradient colors
-	Port        int    // Port to listen to
-	Host        string // Host IP to bind to
-	Server      bool   // Launch an API server when true
-	Rotate      int    // Screen rotation: 0, 90, 180, 270
-	StatusTop   bool   // Place status text at the top instead of bottom
+	Width       int     // Width of window in pixels
+	Height      int     // Height of window in pixels
+	CellSize    int     // Cell size in pixels (square)
+	Seed        int64   // Seed for PRNG
+	Border      bool    // Border around cells
+	Font        string  // Path to TTF to use for status bar
+	FontSize    int     // Size of font in points
+	Rule        string  // Rulestring to use
+	Fps         int     // Frames per Second
+	PatternFile string  // File with initial pattern
+	Pause       bool    // Start the game paused
+	Empty       bool    // Start with empty world
+	Color       bool    // Color the cells based on age
+	Colors      string  // Comma separated color hex triplets
+	Gradient    int     // Gradient algorithm to use
+	MaxAge      int     // Maximum age for gradient colors
+	Port        int     // Port to listen to
+	Host        string  // Host IP to bind to
+	Server      bool    // Launch an API server when true
+	Rotate      int     // Screen rotation: 0, 90, 180, 270
+	StatusTop   bool    // Place status text at the top instead of bottom
+	HeatMap     bool    // Show cell activity as a heat map
+	HeatDecay   float64 // Decay rate of the heat map activity, per generation
+	Trails      bool    // Fade out recently dead cells
+	TrailLength int     // Number of generations for a trail to fade
+	TrailColor  string  // Hex triplet color of newly dead cells
+	Texture     bool    // Draw the cells using a streaming texture
+	PNGEvery    int     // Save every Nth generation as a PNG
+	PNGPrefix   string  // Path and filename prefix for PNG images
+	PNGCellSize int     // Cell size in pixels for PNG images, 0 uses CellSize
+	PNGBorder   bool    // Border around cells in PNG images
+	PNGScheme   string  // Color scheme for PNG images
+	Headless    bool    // Run without a window
+	Generations int64   // Number of generations to run in headless mode
+	RecordGIF   string  // Animated GIF file to record to
+	RecordStart int64   // Generation to start recording at
+	RecordEnd   int64   // Generation to stop recording at, 0 records until exit
+	GIFCellSize int     // Cell size in pixels for the GIF, 0 uses CellSize
+	GIFDelay    int     // Delay between GIF frames in 100ths of a second
+	Y4M         string  // File to write a YUV4MPEG2 video to, - for stdout
+	Y4MWidth    int     // Width of the video, 0 uses the world size
+	Y4MHeight   int     // Height of the video, 0 uses the world size
+	Fullscreen  bool    // Start with a fullscreen window
+	ResizeMode  string  // What to do when the window is resized: world or scale
+	ConfigFile  string  // Configuration file with defaults for the flags
+	Snapshot    string  // File to save and load snapshots with the w and l keys
+	Resume      string  // Snapshot file to resume the game from
+	Playlist    string  // Playlist file or directory of patterns to cycle through
+	PlaylistGen int64   // Default number of generations to show each playlist pattern
+	RulePolicy  string  // Which rule wins when a pattern has one: file or cmdline
+	HexShear    bool    // Shear the world into hexagons for hexagonal rules
+	RuleDir     string  // Directory with rule files
+	PrintMap    bool    // Print -rule as a MAP rule and exit
+	ListRules   bool    // Print the named rules and exit
+	Noise       float64 // Probability of flipping each cell, every generation
 }
 
 /* commandline defaults */
@@ -82,10 +132,58 @@ var cfg = cmdlineArgs{
 	Server:      false,
 	Rotate:      0,
 	StatusTop:   false,
+	HeatMap:     false,
+	HeatDecay:   0.98,
+	Trails:      false,
+	TrailLength: 10,
+	TrailColor:  "#808080",
+	Texture:     false,
+	PNGEvery:    0,
+	PNGPrefix:   "life-",
+	PNGCellSize: 0,
+	PNGBorder:   false,
+	PNGScheme:   "display",
+	Headless:    false,
+	Generations: 0,
+	RecordGIF:   "",
+	RecordStart: 0,
+	RecordEnd:   0,
+	GIFCellSize: 0,
+	GIFDelay:    10,
+	Y4M:         "",
+	Y4MWidth:    0,
+	Y4MHeight:   0,
+	Fullscreen:  false,
+	ResizeMode:  "world",
+	ConfigFile:  "",
+	Snapshot:    "sdl2-life-snapshot.json",
+	Resume:      "",
+	Playlist:    "",
+	PlaylistGen: 500,
+	RulePolicy:  "file",
+	HexShear:    true,
+	RuleDir:     "rules",
+	PrintMap:    false,
+	ListRules:   false,
+	Noise:       0,
 }
 
 /* parseArgs handles parsing the cmdline args and setting values in the global cfg struct */
 func parseArgs() {
+	defineFlags()
+	flag.Parse()
+
+	// Flags on the cmdline take precedence over the environment and config file
+	if err := loadDefaults(); err != nil {
+		log.Fatal(err)
+	}
+	if err := validateArgs(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// defineFlags sets up the cmdline flags for the values in the global cfg struct
+func defineFlags() {
 	flag.IntVar(&cfg.Width, "width", cfg.Width, "Width of window in pixels")
 	flag.IntVar(&cfg.Height, "height", cfg.Height, "Height of window in pixels")
 	flag.IntVar(&cfg.CellSize, "cell", cfg.CellSize, "Cell size in pixels (square)")
@@ -93,7 +191,7 @@ func parseArgs() {
 	flag.BoolVar(&cfg.Border, "border", cfg.Border, "Border around cells")
 	flag.StringVar(&cfg.Font, "font", cfg.Font, "Path to TTF to use for status bar")
 	flag.IntVar(&cfg.FontSize, "font-size", cfg.FontSize, "Size of font in points")
-	flag.StringVar(&cfg.Rule, "rule", cfg.Rule, "Rulestring Bn.../Sn... (B3/S23)")
+	flag.StringVar(&cfg.Rule, "rule", cfg.Rule, "Rulestring Bn.../Sn... (B3/S23), the name of a rule (see -list-rules), or the name of a rule file")
 	flag.IntVar(&cfg.Fps, "fps", cfg.Fps, "Frames per Second update rate (10fps)")
 	flag.StringVar(&cfg.PatternFile, "pattern", cfg.PatternFile, "File with initial pattern to load")
 	flag.BoolVar(&cfg.Pause, "pause", cfg.Pause, "Start the game paused")
@@ -107,12 +205,113 @@ func parseArgs() {
 	flag.BoolVar(&cfg.Server, "server", cfg.Server, "Launch an API server")
 	flag.IntVar(&cfg.Rotate, "rotate", cfg.Rotate, "Rotate screen by 0°, 90°, 180°, or 270°")
 	flag.BoolVar(&cfg.StatusTop, "status-top", cfg.StatusTop, "Status text at the top")
+	flag.BoolVar(&cfg.HeatMap, "heatmap", cfg.HeatMap, "Show cell activity as a heat map")
+	flag.Float64Var(&cfg.HeatDecay, "heat-decay", cfg.HeatDecay, "Heat map decay per generation (0.0-1.0)")
+	flag.BoolVar(&cfg.Trails, "trails", cfg.Trails, "Fade out recently dead cells")
+	flag.IntVar(&cfg.TrailLength, "trail-length", cfg.TrailLength, "Number of generations for a trail to fade")
+	flag.StringVar(&cfg.TrailColor, "trail-color", cfg.TrailColor, "Hex triplet color of newly dead cells")
+	flag.BoolVar(&cfg.Texture, "texture", cfg.Texture, "Draw the cells using a streaming texture")
+	flag.IntVar(&cfg.PNGEvery, "png-every", cfg.PNGEvery, "Save every Nth generation as a PNG")
+	flag.StringVar(&cfg.PNGPrefix, "png-prefix", cfg.PNGPrefix, "Path and filename prefix for PNG images")
+	flag.IntVar(&cfg.PNGCellSize, "png-cell", cfg.PNGCellSize, "Cell size in pixels for PNG images (defaults to -cell)")
+	flag.BoolVar(&cfg.PNGBorder, "png-border", cfg.PNGBorder, "Border around cells in PNG images")
+	flag.StringVar(&cfg.PNGScheme, "png-scheme", cfg.PNGScheme, "PNG color scheme: display, mono, age, or heat")
+	flag.BoolVar(&cfg.Headless, "headless", cfg.Headless, "Run without a window, eg. for saving PNG images")
+	flag.Int64Var(&cfg.Generations, "generations", cfg.Generations, "Number of generations to run in headless mode (0 runs forever)")
+	flag.StringVar(&cfg.RecordGIF, "record-gif", cfg.RecordGIF, "Animated GIF file to record to")
+	flag.Int64Var(&cfg.RecordStart, "record-start", cfg.RecordStart, "Generation to start recording the GIF at")
+	flag.Int64Var(&cfg.RecordEnd, "record-end", cfg.RecordEnd, "Generation to stop recording the GIF at (0 records until exit)")
+	flag.IntVar(&cfg.GIFCellSize, "gif-cell", cfg.GIFCellSize, "Cell size in pixels for the GIF (defaults to -cell)")
+	flag.IntVar(&cfg.GIFDelay, "gif-delay", cfg.GIFDelay, "Delay between GIF frames in 100ths of a second")
+	flag.StringVar(&cfg.Y4M, "y4m", cfg.Y4M, "File to write a YUV4MPEG2 video of every generation to, - for stdout")
+	flag.IntVar(&cfg.Y4MWidth, "y4m-width", cfg.Y4MWidth, "Width of the video (defaults to the world size)")
+	flag.IntVar(&cfg.Y4MHeight, "y4m-height", cfg.Y4MHeight, "Height of the video (defaults to the world size)")
+	flag.BoolVar(&cfg.Fullscreen, "fullscreen", cfg.Fullscreen, "Start with a fullscreen window")
+	flag.StringVar(&cfg.ResizeMode, "resize", cfg.ResizeMode, "When the window is resized change the world size (world) or the cell size (scale)")
+
+	flag.StringVar(&cfg.Snapshot, "snapshot", cfg.Snapshot, "File to save and load snapshots with the w and l keys")
+	flag.StringVar(&cfg.Resume, "resume", cfg.Resume, "Snapshot file to resume the game from")
+	flag.StringVar(&cfg.Playlist, "playlist", cfg.Playlist, "Playlist file or directory of patterns to cycle through")
+	flag.Int64Var(&cfg.PlaylistGen, "playlist-generations", cfg.PlaylistGen, "Default number of generations to show each playlist pattern")
+	flag.StringVar(&cfg.RulePolicy, "rule-policy", cfg.RulePolicy, "Use the pattern file's rule (file) or always use -rule (cmdline)")
+	flag.BoolVar(&cfg.HexShear, "hex-shear", cfg.HexShear, "Shear the world into hexagons for hexagonal (H) rules")
+	flag.StringVar(&cfg.RuleDir, "rule-dir", cfg.RuleDir, "Directory with NAME.rule files for -rule NAME")
+	flag.BoolVar(&cfg.PrintMap, "print-map", cfg.PrintMap, "Print -rule as a MAP rule and exit")
+	flag.BoolVar(&cfg.ListRules, "list-rules", cfg.ListRules, "Print the rules that can be used by name and exit")
+	flag.Float64Var(&cfg.Noise, "noise", cfg.Noise, "Probability of flipping each cell every generation (0.0-1.0)")
+	flag.StringVar(&cfg.ConfigFile, "config", cfg.ConfigFile, "Configuration file with defaults for these flags")
+}
 
-	flag.Parse()
-
+// validateArgs checks the cfg values, returning an error describing the first problem
+func validateArgs() error {
+	if cfg.Width < 1 || cfg.Height < 1 || cfg.CellSize < 1 {
+		return fmt.Errorf("-width, -height, and -cell must be 1 or more")
+	}
+	if cfg.Fps < 1 {
+		return fmt.Errorf("-fps must be 1 or more")
+	}
+	if cfg.FontSize < 1 {
+		return fmt.Errorf("-font-size must be 1 or more")
+	}
+	if _, err := LookupRule(cfg.Rule); err != nil {
+		return fmt.Errorf("-rule %s: %s", cfg.Rule, err)
+	}
+	if _, err := ParseColorTriplets(cfg.Colors); err != nil {
+		return fmt.Errorf("-colors %s: %s", cfg.Colors, err)
+	}
+	if _, err := ParseColorTriplets(cfg.TrailColor); err != nil {
+		return fmt.Errorf("-trail-color %s: %s", cfg.TrailColor, err)
+	}
+	if cfg.Gradient < LinearGradient || cfg.Gradient > BezierGradient {
+		return fmt.Errorf("-gradient only supports 0, 1, and 2")
+	}
+	if cfg.MaxAge < 2 {
+		return fmt.Errorf("-age must be 2 or more")
+	}
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		return fmt.Errorf("-port must be between 1 and 65535")
+	}
 	if cfg.Rotate != 0 && cfg.Rotate != 90 && cfg.Rotate != 180 && cfg.Rotate != 270 {
-		log.Fatal("-rotate only supports 0, 90, 180, and 270")
+		return fmt.Errorf("-rotate only supports 0, 90, 180, and 270")
+	}
+	if cfg.HeatDecay <= 0 || cfg.HeatDecay >= 1 {
+		return fmt.Errorf("-heat-decay must be between 0.0 and 1.0")
+	}
+	if cfg.Noise < 0 || cfg.Noise > 1 {
+		return fmt.Errorf("-noise must be from 0.0 to 1.0")
+	}
+	if cfg.TrailLength < 1 {
+		return fmt.Errorf("-trail-length must be 1 or more")
+	}
+	if cfg.PNGEvery < 0 || cfg.PNGCellSize < 0 {
+		return fmt.Errorf("-png-every and -png-cell cannot be negative")
+	}
+	if cfg.RecordStart < 0 || cfg.RecordEnd < 0 || (cfg.RecordEnd > 0 && cfg.RecordEnd <= cfg.RecordStart) {
+		return fmt.Errorf("-record-end must be after -record-start")
+	}
+	if cfg.GIFCellSize < 0 || cfg.GIFDelay < 0 {
+		return fmt.Errorf("-gif-cell and -gif-delay cannot be negative")
+	}
+	if cfg.Y4MWidth < 0 || cfg.Y4MHeight < 0 || cfg.Y4MWidth%2 != 0 || cfg.Y4MHeight%2 != 0 {
+		return fmt.Errorf("-y4m-width and -y4m-height must be even")
 	}
+	if cfg.ResizeMode != "world" && cfg.ResizeMode != "scale" {
+		return fmt.Errorf("-resize only supports world and scale")
+	}
+	if cfg.PlaylistGen < 1 {
+		return fmt.Errorf("-playlist-generations must be 1 or more")
+	}
+	if cfg.RulePolicy != "file" && cfg.RulePolicy != "cmdline" {
+		return fmt.Errorf("-rule-policy only supports file and cmdline")
+	}
+	if !isImageScheme(cfg.PNGScheme) {
+		return fmt.Errorf("-png-scheme only supports display, mono, age, and heat")
+	}
+	// Without a window or any output a headless run would never stop, and do nothing
+	if cfg.Headless && cfg.Generations == 0 && cfg.PNGEvery == 0 && len(cfg.RecordGIF) == 0 && len(cfg.Y4M) == 0 {
+		return fmt.Errorf("-headless needs -generations, or an output with -png-every, -record-gif, or -y4m")
+	}
+	return nil
 }
 
 // Possible default fonts to search for
@@ -263,42 +462,88 @@ func NewBezierGradient(controls []RGBAColor, maxAge int) Gradient {
 type Cell struct {
 	alive     bool
 	aliveNext bool
+	state     uint8 // State of the cell for multi-state rules, 0 is dead and 1 is alive otherwise
+	stateNext uint8
 
 	x int
 	y int
 
-	age int
+	age   int
+	heat  float64 // Decaying count of recent state changes
+	death int     // Generations since the cell died, 0 if it has not died
 }
 
 // Pattern is used to pass patterns from the API to the game
 type Pattern []string
 
+// PatternInfo holds the metadata from a pattern file
+type PatternInfo struct {
+	Name     string   `json:"name,omitempty"`     // #N in RLE, !Name: in plaintext
+	Author   string   `json:"author,omitempty"`   // #O in RLE, !Author: in plaintext
+	Comments []string `json:"comments,omitempty"` // #C and #D lines, and other ! lines
+	Rule     string   `json:"rule,omitempty"`     // Rule from the pattern, if it has one
+	Gen      int64    `json:"gen,omitempty"`      // Generation of the pattern, from #CXRLE Gen=
+}
+
+// Summary returns the name and author of the pattern, or its first comment if it has no name
+func (p PatternInfo) Summary() string {
+	if len(p.Name) > 0 && len(p.Author) > 0 {
+		return fmt.Sprintf("%s by %s", p.Name, p.Author)
+	} else if len(p.Name) > 0 {
+		return p.Name
+	} else if len(p.Comments) > 0 {
+		return p.Comments[0]
+	}
+	return ""
+}
+
 // LifeGame holds all the global state of the game and the methods to operate on it
 type LifeGame struct {
-	mp        bool
-	erase     bool
-	cells     [][]*Cell // NOTE: This is an array of [row][columns] not x,y coordinates
-	liveCells int
-	age       int64
-	birth     map[int]bool
-	stayAlive map[int]bool
+	mp         bool
+	erase      bool
+	cells      [][]*Cell // NOTE: This is an array of [row][columns] not x,y coordinates
+	liveCells  int
+	age        int64
+	generation int64  // Number of generations since the world was initialized
+	status     string // Last status text drawn
+	rule       Rule
+	steps      []RuleStep // Neighbor counts for each generation, from rule.Steps
+	phase      int        // Index of the step to use for the next generation
+	rng        *rand.Rand // Random numbers for -noise, for the current generation
+	noiseSeed  int64      // Seed for the -noise random numbers
+	counts     [][]int    // Live neighbors of each cell, for Larger than Life rules
+	ageSums    [][]int    // Sum of the ages of the live neighbors, for Larger than Life rules
 
 	// Graphics
 	window   *sdl.Window
 	renderer *sdl.Renderer
 	font     *ttf.Font
+	canvas   Canvas // Where the world is drawn, nil when running headless
 	bg       RGBAColor
 	fg       RGBAColor
+	trail    RGBAColor
+	texture  bool // Draw the cells with one Canvas.DrawCells call
 	rows     int
 	columns  int
 	gradient Gradient
 	pChan    <-chan Pattern
+	sChan    <-chan SnapshotRequest
+	rChan    <-chan RuleRequest
+	gif      *GIFRecorder
+	gifDone  bool // The -record-gif recording has been written
+	video    *Y4MWriter
+	playlist *Playlist
+	info     PatternInfo // Metadata of the current pattern
+	out      io.Writer   // Where the key help and input events are printed
 }
 
 // cleanup will handle cleanup of allocated resources
 func (g *LifeGame) cleanup() {
 	// Clean up all the allocated memory
 
+	if s, ok := g.canvas.(*SDLCanvas); ok {
+		s.Destroy()
+	}
 	g.renderer.Destroy()
 	g.window.Destroy()
 	g.font.Close()
@@ -309,8 +554,50 @@ func (g *LifeGame) cleanup() {
 // InitializeCells resets the world, either randomly or from a pattern file
 func (g *LifeGame) InitializeCells() {
 	g.age = 0
+	g.generation = 0
+	g.SetPatternInfo(PatternInfo{})
+	if cfg.Seed == 0 {
+		g.SeedNoise(time.Now().UnixNano())
+	} else {
+		g.SeedNoise(cfg.Seed)
+	}
 
 	// Fill it with dead cells first
+	g.ClearCells()
+
+	if len(cfg.PatternFile) > 0 {
+		lines, err := ReadPattern(cfg.PatternFile)
+		if err != nil {
+			log.Fatalf("Error reading pattern file: %s", err)
+		}
+		info, err := g.LoadPattern(lines, 0, 0)
+		if err != nil {
+			log.Fatalf("Error reading pattern file: %s", err)
+		}
+		g.SetPatternInfo(info)
+		g.generation = info.Gen
+	}
+
+	if err := g.ApplyPatternRule(g.info); err != nil {
+		log.Fatalf("Failed to parse the rule string (%s): %s\n", cfg.Rule, err)
+	}
+
+	// The random cells need to know the rule's species
+	if len(cfg.PatternFile) == 0 && !cfg.Empty {
+		g.InitializeRandomCells()
+	}
+
+	// Draw initial world
+	g.UpdateCells()
+	g.status = ""
+	if g.canvas != nil {
+		g.Draw(g.status)
+	}
+	g.SaveFrames()
+}
+
+// ClearCells fills the world with new dead cells
+func (g *LifeGame) ClearCells() {
 	g.cells = make([][]*Cell, g.rows)
 	for y := 0; y < g.rows; y++ {
 		for x := 0; x < g.columns; x++ {
@@ -318,48 +605,80 @@ func (g *LifeGame) InitializeCells() {
 			g.cells[y] = append(g.cells[y], c)
 		}
 	}
+}
 
-	if len(cfg.PatternFile) > 0 {
-		// Read all of the pattern file for parsing
-		f, err := os.Open(cfg.PatternFile)
-		if err != nil {
-			log.Fatalf("Error reading pattern file: %s", err)
-		}
-		defer f.Close()
+// ReadPattern reads all of the lines from a pattern file
+func ReadPattern(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
 
-		scanner := bufio.NewScanner(f)
-		var lines []string
-		for scanner.Scan() {
-			lines = append(lines, scanner.Text())
-		}
-		if len(lines) == 0 {
-			log.Fatalf("%s is empty.", cfg.PatternFile)
-		}
+	scanner := bufio.NewScanner(f)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+	return lines, nil
+}
 
-		if strings.HasPrefix(lines[0], "#Life 1.05") {
-			err = g.ParseLife105(lines)
-		} else if strings.HasPrefix(lines[0], "#Life 1.06") {
-			log.Fatal("Life 1.06 file format is not supported")
-		} else if isRLE(lines) {
-			err = g.ParseRLE(lines, 0, 0)
-		} else {
-			err = g.ParsePlaintext(lines)
-		}
+// LoadPattern parses the pattern and places it on the world
+// The pattern is moved by x, y from its normal position
+func (g *LifeGame) LoadPattern(lines []string, x, y int) (PatternInfo, error) {
+	if len(lines) == 0 {
+		return PatternInfo{}, fmt.Errorf("Empty pattern")
+	}
+	if strings.HasPrefix(lines[0], "#Life 1.05") {
+		return g.ParseLife105(lines, x, y)
+	} else if strings.HasPrefix(lines[0], "#Life 1.06") {
+		return PatternInfo{}, fmt.Errorf("Life 1.06 file format is not supported")
+	} else if isRLE(lines) {
+		return g.ParseRLE(lines, x, y)
+	}
+	return g.ParsePlaintext(lines, x, y)
+}
 
-		if err != nil {
-			log.Fatalf("Error reading pattern file: %s", err)
-		}
-	} else if !cfg.Empty {
-		g.InitializeRandomCells()
+// SetPatternInfo sets the metadata of the pattern being shown and updates the window title
+func (g *LifeGame) SetPatternInfo(info PatternInfo) {
+	g.info = info
+	if g.window == nil {
+		return
+	}
+	title := "Conway's Game of Life"
+	if summary := info.Summary(); len(summary) > 0 {
+		title = summary + " - " + title
 	}
+	g.window.SetTitle(title)
+}
 
-	var err error
-	if g.birth, g.stayAlive, err = ParseRulestring(cfg.Rule); err != nil {
-		log.Fatalf("Failed to parse the rule string (%s): %s\n", cfg.Rule, err)
+// ApplyPatternRule switches the game to the pattern's rule, and makes it the current pattern
+// If the pattern has no rule, or -rule-policy is cmdline, the current cfg.Rule is used.
+func (g *LifeGame) ApplyPatternRule(info PatternInfo) error {
+	rule := cfg.Rule
+	if len(info.Rule) > 0 && cfg.RulePolicy == "file" {
+		rule = info.Rule
 	}
+	if err := g.UseRule(rule); err != nil {
+		return err
+	}
+	// The pattern's information has the name of the rule it is run with
+	info.Rule = g.info.Rule
+	g.SetPatternInfo(info)
+	return nil
+}
 
-	// Draw initial world
-	g.Draw("")
+// SetRule switches the game to a new rule, starting with its first step
+func (g *LifeGame) SetRule(r Rule) {
+	g.rule = r
+	g.steps = r.Steps()
+	g.phase = 0
 }
 
 // TranslateXY move the x, y coordinates so that 0, 0 is the center of the world
@@ -378,13 +697,23 @@ func (g *LifeGame) TranslateXY(x, y int) (int, int) {
 // SetCellState sets the cell alive state
 // it also wraps the x and y at the edges and returns the new value
 func (g *LifeGame) SetCellState(x, y int, alive bool) (int, int) {
+	if alive {
+		return g.SetCell(x, y, 1)
+	}
+	return g.SetCell(x, y, 0)
+}
+
+// SetCell sets the cell to a state, any state other than 0 is alive
+// it also wraps the x and y at the edges and returns the new value
+func (g *LifeGame) SetCell(x, y int, state uint8) (int, int) {
 	x = x % g.columns
 	y = y % g.rows
-	g.cells[y][x].alive = alive
-	g.cells[y][x].aliveNext = alive
+	c := g.cells[y][x]
+	c.state, c.stateNext = state, state
+	c.alive, c.aliveNext = state != 0, state != 0
 
-	if !alive {
-		g.cells[y][x].age = 0
+	if !c.alive {
+		c.age = 0
 	}
 
 	return x, y
@@ -405,17 +734,24 @@ func (g *LifeGame) PrintCellDetails(x, y int32) {
 }
 
 // FillDead makes sure the rest of a line, width long, is filled with dead cells
-// xEdge is the left side of the box of width length
-// x is the starting point for the first line, any further lines start at xEdge
-func (g *LifeGame) FillDead(xEdge, x, y, width, height int) {
+// x, y is the top left corner of the box of width length
+// col, row is the starting point for the first line, any further lines start at col 0
+// Nothing past the edge of the world is filled, it would wrap around onto cells that
+// have already been filled.
+func (g *LifeGame) FillDead(x, y, col, row, width, height int) {
+	if height > g.rows+1 {
+		height = g.rows + 1
+	}
 	for i := 0; i < height; i++ {
-		jlen := width - x
-		for j := 0; j < jlen; j++ {
-			x, y = g.SetCellState(x, y, false)
-			x++
+		end := width
+		if end-col > g.columns {
+			end = col + g.columns
 		}
-		y++
-		x = xEdge
+		for ; col < end; col++ {
+			g.SetCellState(x+col, y+row, false)
+		}
+		row++
+		col = 0
 	}
 }
 
@@ -433,95 +769,105 @@ func (g *LifeGame) InitializeRandomCells() {
 
 	for y := 0; y < g.rows; y++ {
 		for x := 0; x < g.columns; x++ {
-			g.SetCellState(x, y, rand.Float64() < threshold)
+			if rand.Float64() >= threshold {
+				g.SetCellState(x, y, false)
+			} else if g.rule.Species > 0 {
+				g.SetCell(x, y, uint8(rand.Intn(g.rule.Species)+1))
+			} else {
+				g.SetCellState(x, y, true)
+			}
 		}
 	}
 }
 
 // ParseLife105 pattern file
 // #D Descriptions lines (0+)
-// #R Rule line (0/1)
-// #P -1 4 (Upper left corner, required, center is 0,0)
-// The pattern is . for dead and * for live
-func (g *LifeGame) ParseLife105(lines []string) error {
-	var x, y int
+// #N Normal rules, Conway's B3/S23 (0/1)
+// #R Rule line, sss/bbb (0/1)
+// #P -1 4 (Upper left corner of the following block of lines, center is 0,0)
+// The pattern is . for dead and * for live, there may be more than one #P block
+// xOffset, yOffset move the pattern from its #P position
+func (g *LifeGame) ParseLife105(lines []string, xOffset, yOffset int) (PatternInfo, error) {
+	var info PatternInfo
+	var x, y, row int
 	var err error
 	for _, line := range lines {
-		if strings.HasPrefix(line, "#D") || strings.HasPrefix(line, "#Life") {
-			continue
+		line = strings.TrimRight(line, " \t\r")
+		if strings.HasPrefix(line, "#D") {
+			info.Comments = append(info.Comments, strings.TrimSpace(line[2:]))
 		} else if strings.HasPrefix(line, "#N") {
-			// Use default rules (from the cmdline in this case)
-			continue
-		} else if strings.HasPrefix(line, "#R ") {
-			// TODO Parse rule and return it or setup cfg.Rule
-			// Format is: sss/bbb where s is stay alive and b are birth values
-			// Need to flip it to Bbbb/Ssss format
-
-			// Make sure the rule has a / in it
-			if !strings.Contains(line, "/") {
-				return fmt.Errorf("ERROR: Rule must contain /")
+			info.Rule = "B3/S23"
+		} else if strings.HasPrefix(line, "#R") {
+			if info.Rule, err = parseLife105Rule(line[2:]); err != nil {
+				return info, err
 			}
-
-			fields := strings.Split(line[3:], "/")
+		} else if strings.HasPrefix(line, "#P") {
+			// Start of a new block
+			fields := strings.Fields(line[2:])
 			if len(fields) != 2 {
-				return fmt.Errorf("ERROR: Problem splitting rule on /")
+				return info, fmt.Errorf("Cannot parse position line: %s", line)
 			}
-
-			var stay, birth int
-			if stay, err = strconv.Atoi(fields[0]); err != nil {
-				return fmt.Errorf("Error parsing alive value: %s", err)
-			}
-
-			if birth, err = strconv.Atoi(fields[1]); err != nil {
-				return fmt.Errorf("Error parsing birth value: %s", err)
-			}
-
-			cfg.Rule = fmt.Sprintf("B%d/S%d", birth, stay)
-		} else if strings.HasPrefix(line, "#P") {
-			// Initial position
-			fields := strings.Split(line, " ")
-			if len(fields) != 3 {
-				return fmt.Errorf("Cannot parse position line: %s", line)
+			if x, err = strconv.Atoi(fields[0]); err != nil {
+				return info, fmt.Errorf("Error parsing position: %s", err)
 			}
 			if y, err = strconv.Atoi(fields[1]); err != nil {
-				return fmt.Errorf("Error parsing position: %s", err)
-			}
-			if x, err = strconv.Atoi(fields[2]); err != nil {
-				return fmt.Errorf("Error parsing position: %s", err)
+				return info, fmt.Errorf("Error parsing position: %s", err)
 			}
-
-			// Move to 0, 0 at the center of the world
-			x, y = g.TranslateXY(x, y)
+			row = 0
+		} else if strings.HasPrefix(line, "#") {
+			// #Life header and any other unknown lines
+			continue
 		} else {
 			// Parse the line, error if it isn't . or *
-			xLine := x
-			for _, c := range line {
+			for col, c := range line {
 				if c != '.' && c != '*' {
-					return fmt.Errorf("Illegal characters in pattern: %s", line)
+					return info, fmt.Errorf("Illegal characters in pattern: %s", line)
 				}
-				xLine, y = g.SetCellState(xLine, y, c == '*')
-				xLine++
+				// Move to 0, 0 at the center of the world
+				cx, cy := g.TranslateXY(x+xOffset+col, y+yOffset+row)
+				g.SetCellState(cx, cy, c == '*')
 			}
-			y++
+			row++
 		}
 	}
-	return nil
+	return info, nil
+}
+
+// parseLife105Rule converts a Life 1.05 sss/bbb rule to the canonical Bbbb/Ssss format
+// Anything after the rule is ignored.
+func parseLife105Rule(rule string) (string, error) {
+	fields := strings.Fields(rule)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("Missing rule after #R")
+	}
+	r, err := ParseRulestring(fields[0])
+	if err != nil {
+		return "", err
+	}
+	return r.Rulestring, nil
 }
 
 // ParsePlaintext pattern file
 // The header has already been read from the buffer when this is called
-// This is a bit more generic than the spec, skip lines starting with !
+// This is a bit more generic than the spec, lines starting with ! are comments
 // and assume the pattern is . for dead cells any anything else for live.
-func (g *LifeGame) ParsePlaintext(lines []string) error {
-	var x, y int
+// Optional x, y starting position, relative to the center
+func (g *LifeGame) ParsePlaintext(lines []string, x, y int) (PatternInfo, error) {
+	var info PatternInfo
 
 	// Move x, y to center of field
-	x = g.columns / 2
-	y = g.rows / 2
+	x, y = g.TranslateXY(x, y)
 
 	for _, line := range lines {
 		if strings.HasPrefix(line, "!") {
-			continue
+			comment := strings.TrimSpace(line[1:])
+			if strings.HasPrefix(comment, "Name:") {
+				info.Name = strings.TrimSpace(comment[5:])
+			} else if strings.HasPrefix(comment, "Author:") {
+				info.Author = strings.TrimSpace(comment[7:])
+			} else if len(comment) > 0 {
+				info.Comments = append(info.Comments, comment)
+			}
 		} else {
 			// Parse the line, . is dead, anything else is alive.
 			xLine := x
@@ -533,7 +879,7 @@ func (g *LifeGame) ParsePlaintext(lines []string) error {
 		}
 	}
 
-	return nil
+	return info, nil
 }
 
 // isRLEPattern checks the lines to determine if it is a RLE pattern
@@ -548,104 +894,258 @@ func isRLE(lines []string) bool {
 
 // ParseRLE pattern file
 // Parses files matching the RLE specification - https://conwaylife.com/wiki/Run_Length_Encoded
-// Optional x, y starting position for later use
-func (g *LifeGame) ParseRLE(lines []string, x, y int) error {
-	// Move to 0, 0 at the center of the world
-	x, y = g.TranslateXY(x, y)
+// The pattern is centered unless it has a #P, #R, or #CXRLE Pos= position for its top left
+// corner. Optional x, y move the pattern from that position.
+func (g *LifeGame) ParseRLE(lines []string, x, y int) (PatternInfo, error) {
+	var info PatternInfo
 
 	var header []string
 	var first int
+	var posX, posY int
+	var hasPos bool
 	for i, line := range lines {
 		header = rleHeaderRegex.FindStringSubmatch(line)
 		if len(header) > 0 {
 			first = i + 1
 			break
 		}
+		if len(line) == 0 {
+			continue
+		}
 		// All lines before the header must be a # line
 		if line[0] != '#' {
-			return fmt.Errorf("Incorrect or missing RLE header")
+			return info, fmt.Errorf("Incorrect or missing RLE header")
+		}
+		if len(line) < 2 {
+			continue
+		}
+		text := strings.TrimSpace(line[2:])
+		switch line[1] {
+		case 'N':
+			info.Name = text
+		case 'O':
+			info.Author = text
+		case 'r':
+			// The rule is in S/B order, eg. 23/3, store it in the canonical B/S order
+			info.Rule = text
+			if r, err := ParseRulestring(text); err == nil {
+				info.Rule = r.Rulestring
+			}
+		case 'P', 'R':
+			// XLife style position of the top left corner, relative to the center
+			fields := strings.Fields(text)
+			if len(fields) != 2 {
+				return info, fmt.Errorf("Cannot parse position line: %s", line)
+			}
+			var err error
+			if posX, err = strconv.Atoi(fields[0]); err != nil {
+				return info, fmt.Errorf("Error parsing position: %s", err)
+			}
+			if posY, err = strconv.Atoi(fields[1]); err != nil {
+				return info, fmt.Errorf("Error parsing position: %s", err)
+			}
+			hasPos = true
+		case 'C', 'c':
+			if !strings.HasPrefix(text, "XRLE") {
+				info.Comments = append(info.Comments, text)
+				break
+			}
+			xrlePos, err := parseXRLE(text, &posX, &posY, &info.Gen)
+			if err != nil {
+				return info, err
+			}
+			hasPos = hasPos || xrlePos
 		}
 	}
 	if len(header) < 3 {
-		return fmt.Errorf("Incorrect or missing RLE header")
+		return info, fmt.Errorf("Incorrect or missing RLE header")
 	}
 	if first > len(lines)-1 {
-		return fmt.Errorf("Missing lines after RLE header")
+		return info, fmt.Errorf("Missing lines after RLE header")
 	}
 	width, err := strconv.Atoi(header[1])
 	if err != nil {
-		return fmt.Errorf("Error parsing width: %s", err)
+		return info, fmt.Errorf("Error parsing width: %s", err)
 	}
 	height, err := strconv.Atoi(header[2])
 	if err != nil {
-		return fmt.Errorf("Error parsing height: %s", err)
+		return info, fmt.Errorf("Error parsing height: %s", err)
+	}
+	if width > maxPatternSize || height > maxPatternSize {
+		return info, fmt.Errorf("RLE pattern is larger than %d", maxPatternSize)
+	}
+
+	// The header's rule replaces any #r rule, -rule-policy decides whether it is used
+	if len(header) == 4 && len(strings.TrimSpace(header[3])) > 0 {
+		info.Rule = strings.TrimSpace(header[3])
 	}
 
-	// Were there rules? Use them. TODO override if cmdline rules passed in
-	if len(header) == 4 {
-		cfg.Rule = header[3]
+	// Move to 0, 0 at the center of the world
+	if hasPos {
+		x, y = g.TranslateXY(posX+x, posY+y)
+	} else {
+		x, y = g.TranslateXY(x-width/2, y-height/2)
 	}
 
+	// col, row are relative to the top left corner so that wrapping at the edges does not
+	// change the size of the pattern. Cells outside the width and height from the header
+	// are ignored.
 	count := 0
-	xLine := x
-	yStart := y
+	col, row := 0, 0
+	var prefix rune
 	for _, line := range lines[first:] {
 		for _, c := range line {
+			if prefix != 0 && (c < 'A' || c > 'X') {
+				return info, fmt.Errorf("Multi-state cell %c must be followed by A-X", prefix)
+			}
 			if c == '$' {
 				// End of this line (which can have a count)
 				if count == 0 {
 					count = 1
 				}
 				// Blank cells to the edge of the pattern, and full empty lines
-				g.FillDead(x, xLine, y, width, count)
+				if count > height-row {
+					count = height - row
+				}
+				g.FillDead(x, y, col, row, width, count)
 
-				xLine = x
-				y = y + count
+				col = 0
+				row = row + count
 				count = 0
 				continue
 			}
 			if c == '!' {
 				// Finished
 				// Fill in any remaining space with dead cells
-				g.FillDead(x, xLine, y, width, height-(y-yStart))
-				return nil
+				g.FillDead(x, y, col, row, width, height-row)
+				return info, nil
 			}
 
 			// Is it a digit?
 			digit, err := strconv.Atoi(string(c))
 			if err == nil {
 				count = (count * 10) + digit
+				if count > maxPatternSize {
+					return info, fmt.Errorf("RLE run count is larger than %d", maxPatternSize)
+				}
 				continue
 			}
 
+			// Multi-state cells are A-X for 1-24, with a prefix of p-y for 25-255
+			if c >= 'p' && c <= 'y' {
+				prefix = c
+				continue
+			}
+			state, err := rleState(prefix, c)
+			if err != nil {
+				return info, err
+			}
+			prefix = 0
+
 			if count == 0 {
 				count = 1
 			}
 
-			for i := 0; i < count; i++ {
-				xLine, y = g.SetCellState(xLine, y, c != 'b')
-				xLine++
+			// More than a row of the world wraps around onto the same cells
+			run := count
+			if run > width-col {
+				run = width - col
+			}
+			if run > g.columns {
+				run = g.columns
 			}
+			if row < height {
+				for i := 0; i < run; i++ {
+					g.SetCell(x+col+i, y+row, state)
+				}
+			}
+			col += count
 			count = 0
 		}
 	}
-	return nil
+	return info, nil
+}
+
+// rleState returns the state of an RLE cell, b and . are dead, and o is alive
+// Any other character is also alive, unless it is one of the multi-state cells.
+func rleState(prefix, c rune) (uint8, error) {
+	switch {
+	case c == 'b' || c == '.':
+		return 0, nil
+	case c >= 'A' && c <= 'X':
+		state := int(c-'A') + 1
+		if prefix != 0 {
+			state += int(prefix-'o') * 24
+		}
+		if state > 255 {
+			return 0, fmt.Errorf("Multi-state cell %c%c is larger than 255", prefix, c)
+		}
+		return uint8(state), nil
+	}
+	return 1, nil
+}
+
+// parseXRLE parses a #CXRLE line, which may include the position of the top left corner
+// and the generation of the pattern. It returns true if there was a position.
+func parseXRLE(line string, x, y *int, gen *int64) (bool, error) {
+	var hasPos bool
+	for _, field := range strings.Fields(line)[1:] {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "Pos":
+			pos := strings.Split(kv[1], ",")
+			if len(pos) != 2 {
+				return false, fmt.Errorf("Cannot parse XRLE position: %s", kv[1])
+			}
+			var err error
+			if *x, err = strconv.Atoi(pos[0]); err != nil {
+				return false, fmt.Errorf("Error parsing XRLE position: %s", err)
+			}
+			if *y, err = strconv.Atoi(pos[1]); err != nil {
+				return false, fmt.Errorf("Error parsing XRLE position: %s", err)
+			}
+			hasPos = true
+		case "Gen":
+			var err error
+			if *gen, err = strconv.ParseInt(kv[1], 10, 64); err != nil {
+				return false, fmt.Errorf("Error parsing XRLE generation: %s", err)
+			}
+		}
+	}
+	return hasPos, nil
 }
 
 // checkState determines the state of the cell for the next tick of the game.
 func (g *LifeGame) checkState(c *Cell) {
-	liveCount, avgAge := g.liveNeighbors(c)
-	if c.alive {
-		// Stay alive if the number of neighbors is in stayAlive
-		_, c.aliveNext = g.stayAlive[liveCount]
+	step := g.steps[g.phase]
+	if g.rule.Table != nil {
+		c.stateNext = g.rule.Table.Next(g.tableNeighbors(c))
+		c.aliveNext = c.stateNext != 0
+	} else if step.Map != nil {
+		index, avgAge := g.mapNeighbors(c)
+		if c.aliveNext = step.Map[index]; c.aliveNext && !c.alive {
+			c.age = avgAge
+		}
+	} else if liveCount, avgAge := g.liveNeighbors(c); c.alive {
+		// Stay alive if the number of neighbors is in StayAlive
+		_, c.aliveNext = step.StayAlive[liveCount]
+		if g.rule.Species > 0 && c.aliveNext {
+			c.stateNext = g.species(c.state)
+		}
 	} else {
-		// Birth a new cell if number of neighbors is in birth
-		_, c.aliveNext = g.birth[liveCount]
+		// Birth a new cell if number of neighbors is in Birth
+		_, c.aliveNext = step.Birth[liveCount]
 
 		// New cells inherit their age from parents
 		// TODO make this optional
 		if c.aliveNext {
 			c.age = avgAge
+			if g.rule.Species > 0 {
+				c.stateNext = g.majoritySpecies(c)
+			}
 		}
 	}
 
@@ -654,24 +1154,25 @@ func (g *LifeGame) checkState(c *Cell) {
 	} else {
 		c.age = 0
 	}
+	if !c.aliveNext {
+		c.stateNext = 0
+	} else if g.rule.Table == nil && g.rule.Species == 0 {
+		c.stateNext = 1
+	}
 }
 
 // liveNeighbors returns the number of live neighbors for a cell and their average age
 func (g *LifeGame) liveNeighbors(c *Cell) (int, int) {
 	var liveCount int
 	var ageSum int
-	add := func(x, y int) {
+	if g.rule.Range > 0 {
+		// Counted for all the cells by CountRange
+		liveCount, ageSum = g.counts[c.y][c.x], g.ageSums[c.y][c.x]
+	}
+	for _, offset := range g.rule.Neighborhood {
 		// If we're at an edge, check the other side of the board.
-		if y == len(g.cells) {
-			y = 0
-		} else if y == -1 {
-			y = len(g.cells) - 1
-		}
-		if x == len(g.cells[y]) {
-			x = 0
-		} else if x == -1 {
-			x = len(g.cells[y]) - 1
-		}
+		x := ((c.x+offset[0])%g.columns + g.columns) % g.columns
+		y := ((c.y+offset[1])%g.rows + g.rows) % g.rows
 
 		if g.cells[y][x].alive {
 			liveCount++
@@ -679,227 +1180,373 @@ func (g *LifeGame) liveNeighbors(c *Cell) (int, int) {
 		}
 	}
 
-	add(c.x-1, c.y)   // To the left
-	add(c.x+1, c.y)   // To the right
-	add(c.x, c.y+1)   // up
-	add(c.x, c.y-1)   // down
-	add(c.x-1, c.y+1) // top-left
-	add(c.x+1, c.y+1) // top-right
-	add(c.x-1, c.y-1) // bottom-left
-	add(c.x+1, c.y-1) // bottom-right
-
 	if liveCount > 0 {
 		return liveCount, int(ageSum / liveCount)
 	}
 	return liveCount, 0
 }
 
-// SetColorFromAge uses the cell's age to color it
-func (g *LifeGame) SetColorFromAge(age int) {
-	if age >= len(g.gradient.points) {
-		age = len(g.gradient.points) - 1
+// GradientColor returns the gradient color at position i, clamped to the end of the gradient
+func (g *LifeGame) GradientColor(i int) RGBAColor {
+	if i >= len(g.gradient.points) {
+		i = len(g.gradient.points) - 1
+	}
+	return g.gradient.points[i]
+}
+
+// HeatColor returns the gradient color for a cell's recent activity
+func (g *LifeGame) HeatColor(heat float64) RGBAColor {
+	// A cell that changes every generation settles at 1/(1-decay)
+	level := heat * (1 - cfg.HeatDecay)
+	if level > 1 {
+		level = 1
+	}
+	return g.GradientColor(int(level * float64(len(g.gradient.points)-1)))
+}
+
+// TrailColor returns the color of a dead cell, fading from the trail color to the background
+func (g *LifeGame) TrailColor(death int) RGBAColor {
+	t := float64(death-1) / float64(cfg.TrailLength)
+	fade := func(from, to uint8) uint8 {
+		return uint8(float64(from) + t*(float64(to)-float64(from)))
+	}
+	return RGBAColor{fade(g.trail.r, g.bg.r), fade(g.trail.g, g.bg.g), fade(g.trail.b, g.bg.b), 255}
+}
+
+// CellColor returns the color to draw a cell with
+// It returns false if the cell should be left as background. The states of multi-state
+// rules, and species, always use their own colors, -color only changes 2 state rules.
+func (g *LifeGame) CellColor(c *Cell) (RGBAColor, bool) {
+	if c.alive {
+		if g.rule.Table != nil {
+			return g.StateColor(c.state), true
+		}
+		if g.rule.Species > 0 {
+			return g.SpeciesColor(c.state), true
+		}
+		if cfg.Color {
+			return g.GradientColor(c.age), true
+		}
+		return g.fg, true
+	}
+	if cfg.Trails && c.death > 0 && c.death <= cfg.TrailLength {
+		return g.TrailColor(c.death), true
+	}
+	if cfg.HeatMap && c.heat > heatThreshold {
+		return g.HeatColor(c.heat), true
+	}
+	return g.bg, false
+}
+
+// StateColor returns the color of a live state of a multi-state rule
+// The colors come from the rule's @COLORS, or are spread along the -colors gradient.
+func (g *LifeGame) StateColor(state uint8) RGBAColor {
+	t := g.rule.Table
+	if color, ok := t.Colors[state]; ok {
+		return color
+	}
+	if t.States <= 2 {
+		return g.fg
+	}
+	i := int(state) - 1
+	if i > t.States-2 {
+		i = t.States - 2
+	}
+	return g.GradientColor(i * (len(g.gradient.points) - 1) / (t.States - 2))
+}
+
+// UpdateCells moves all the cells to their next state
+// and accumulates the activity used by the heat map and trails
+func (g *LifeGame) UpdateCells() {
+	for y := range g.cells {
+		for _, c := range g.cells[y] {
+			c.heat *= cfg.HeatDecay
+			if c.alive != c.aliveNext {
+				c.heat++
+			}
+
+			if c.aliveNext {
+				c.death = 0
+			} else if c.alive {
+				c.death = 1
+			} else if c.death > 0 && c.death <= cfg.TrailLength {
+				c.death++
+			}
+			c.alive = c.aliveNext
+			c.state = c.stateNext
+		}
 	}
-	color := g.gradient.points[age]
-	g.renderer.SetDrawColor(color.r, color.g, color.b, color.a)
 }
 
 // Draw draws the current state of the world
 func (g *LifeGame) Draw(status string) {
 	// Clear the world to the background color
-	g.renderer.SetDrawColor(g.bg.r, g.bg.g, g.bg.b, g.bg.a)
-	g.renderer.Clear()
-	g.renderer.SetDrawColor(g.fg.r, g.fg.g, g.fg.b, g.fg.a)
+	g.canvas.Clear(g.bg)
+
+	// The texture cannot be sheared, draw the cells individually instead
+	if g.texture && !g.Sheared() {
+		err := g.DrawTexture()
+		if err == nil {
+			g.UpdateStatus(status)
+			g.canvas.Present()
+			return
+		}
+		// Fall back to drawing the cells one at a time
+		log.Printf("Failed to draw texture, drawing cells individually: %s\n", err)
+		g.texture = false
+	}
+
 	for y := range g.cells {
 		for _, c := range g.cells[y] {
-			c.alive = c.aliveNext
-			if !c.alive {
+			color, ok := g.CellColor(c)
+			if !ok {
 				continue
 			}
-			if cfg.Color {
-				g.SetColorFromAge(c.age)
-			}
-			g.DrawCell(*c)
+			g.DrawCell(*c, color)
 		}
 	}
-	// Default to background color
-	g.renderer.SetDrawColor(g.bg.r, g.bg.g, g.bg.b, g.bg.a)
 
 	g.UpdateStatus(status)
 
-	g.renderer.Present()
+	g.canvas.Present()
 }
 
-// DrawCell draws a new cell on an empty background
-func (g *LifeGame) DrawCell(c Cell) {
-	var x, y int32
-	if cfg.Rotate == 0 {
-		if cfg.StatusTop {
-			y = int32(c.y*cfg.CellSize + 4 + g.font.Height())
-		} else {
-			y = int32(c.y * cfg.CellSize)
-		}
-		x = int32(c.x * cfg.CellSize)
-	} else if cfg.Rotate == 180 {
+// StatusHeight returns the space used by the status text, or 0 if there is no status font
+func (g *LifeGame) StatusHeight() int {
+	if g.canvas == nil {
+		return 0
+	}
+	return 4 + g.canvas.FontHeight()
+}
+
+// WorldOrigin returns the window coordinates of the top left corner of the world
+// The status text is placed above, below, or to the side of it depending on the rotation
+func (g *LifeGame) WorldOrigin() (int32, int32) {
+	status := int32(g.StatusHeight())
+	if cfg.Rotate == 0 && cfg.StatusTop {
+		return 0, status
+	} else if cfg.Rotate == 180 && !cfg.StatusTop {
 		// Invert top and bottom
-		if cfg.StatusTop {
-			y = int32(c.y * cfg.CellSize)
-		} else {
-			y = int32(c.y*cfg.CellSize + 4 + g.font.Height())
-		}
-		x = int32(c.x * cfg.CellSize)
-	} else if cfg.Rotate == 90 {
-		if cfg.StatusTop {
-			x = int32(c.x*cfg.CellSize + 4 + g.font.Height())
-		} else {
-			x = int32(c.x * cfg.CellSize)
-		}
-		y = int32(c.y * cfg.CellSize)
-	} else if cfg.Rotate == 270 {
-		if cfg.StatusTop {
-			x = int32(c.x * cfg.CellSize)
-		} else {
-			x = int32(c.x*cfg.CellSize + 4 + g.font.Height())
+		return 0, status
+	} else if cfg.Rotate == 90 && cfg.StatusTop {
+		return status, 0
+	} else if cfg.Rotate == 270 && !cfg.StatusTop {
+		return status, 0
+	}
+	return 0, 0
+}
+
+// Sheared returns true when the world is drawn sheared into hexagons
+func (g *LifeGame) Sheared() bool {
+	return cfg.HexShear && g.rule.Hex
+}
+
+// CellRects returns the rectangles covering a cell, relative to the top left corner of the world
+// When the world is sheared each row is drawn half a cell to the left of the one above it,
+// so that the 6 hexagonal neighbors of a cell are the ones touching it. Cells that are
+// moved past the right edge wrap around to the left edge, using a second rectangle.
+func (g *LifeGame) CellRects(c Cell, cellSize int, border bool) []image.Rectangle {
+	x := c.x * cellSize
+	y := c.y * cellSize
+	size := cellSize
+	if border {
+		x, y, size = x+1, y+1, size-2
+	}
+	if size < 0 {
+		size = 0
+	}
+
+	if g.Sheared() {
+		worldWidth := g.columns * cellSize
+		x = (x + (g.rows-1-c.y)*cellSize/2) % worldWidth
+		if x+size > worldWidth {
+			return []image.Rectangle{
+				image.Rect(x, y, worldWidth, y+size),
+				image.Rect(0, y, x+size-worldWidth, y+size),
+			}
 		}
-		y = int32(c.y * cfg.CellSize)
 	}
+	return []image.Rectangle{image.Rect(x, y, x+size, y+size)}
+}
 
-	if cfg.Border {
-		g.renderer.FillRect(&sdl.Rect{x + 1, y + 1, int32(cfg.CellSize - 2), int32(cfg.CellSize - 2)})
-	} else {
-		g.renderer.FillRect(&sdl.Rect{x, y, int32(cfg.CellSize), int32(cfg.CellSize)})
+// DrawCell draws a new cell on an empty background
+func (g *LifeGame) DrawCell(c Cell, color RGBAColor) {
+	originX, originY := g.WorldOrigin()
+	for _, r := range g.CellRects(c, cfg.CellSize, cfg.Border) {
+		g.canvas.FillRect(originX+int32(r.Min.X), originY+int32(r.Min.Y), int32(r.Dx()), int32(r.Dy()), color)
 	}
 }
 
+// DrawTexture draws all of the cells with one pixel per cell, scaled up by the canvas
+// This is much faster than drawing each cell as a rectangle when the canvas uses a texture.
+func (g *LifeGame) DrawTexture() error {
+	x, y := g.WorldOrigin()
+	return g.canvas.DrawCells(g.RenderImage(1, false, "display"), x, y, cfg.CellSize, cfg.Border, g.bg)
+}
+
 // UpdateCell redraws an existing cell, optionally erasing it
 func (g *LifeGame) UpdateCell(x, y int, erase bool) {
 	g.cells[y][x].alive = !erase
+	g.cells[y][x].state = 0
+	if !erase {
+		g.cells[y][x].state = 1
+	}
 
 	// Update the image right now
 	if erase {
-		g.renderer.SetDrawColor(g.bg.r, g.bg.g, g.bg.b, g.bg.a)
+		g.DrawCell(*g.cells[y][x], g.bg)
 	} else {
-		g.renderer.SetDrawColor(g.fg.r, g.fg.g, g.fg.b, g.fg.a)
+		g.DrawCell(*g.cells[y][x], g.fg)
 	}
-	g.DrawCell(*g.cells[y][x])
-
-	// Default to background color
-	g.renderer.SetDrawColor(g.bg.r, g.bg.g, g.bg.b, g.bg.a)
-	g.renderer.Present()
+	g.canvas.Present()
 }
 
 // UpdateStatus draws the status bar
+// It is centered along the edge of the window, and rotated to match the window
 func (g *LifeGame) UpdateStatus(status string) {
 	if len(status) == 0 {
 		return
 	}
-	text, err := g.font.RenderUTF8Solid(status, sdl.Color{255, 255, 255, 255})
-	if err != nil {
-		log.Printf("Failed to render text: %s\n", err)
-		return
-	}
-	defer text.Free()
-
-	texture, err := g.renderer.CreateTextureFromSurface(text)
-	if err != nil {
-		log.Printf("Failed to render text: %s\n", err)
-		return
-	}
-	defer texture.Destroy()
-
-	w, h, err := g.font.SizeUTF8(status)
+	w, h, err := g.canvas.TextSize(status)
 	if err != nil {
 		log.Printf("Failed to get size: %s\n", err)
 		return
 	}
 
+	// x, y is the top left corner of the text after it has been rotated
+	var x, y, angle int
 	if cfg.Rotate == 0 {
-		var y int32
 		if cfg.StatusTop {
 			y = 2
 		} else {
-			y = int32(cfg.Height - 2 - h)
-		}
-
-		x := int32((cfg.Width - w) / 2)
-		rect := &sdl.Rect{x, y, int32(w), int32(h)}
-		if err = g.renderer.Copy(texture, nil, rect); err != nil {
-			log.Printf("Failed to copy texture: %s\n", err)
-			return
+			y = cfg.Height - 2 - h
 		}
+		x = (cfg.Width - w) / 2
 	} else if cfg.Rotate == 180 {
-		var y int32
 		if cfg.StatusTop {
-			y = int32(cfg.Height - 2 - h)
+			y = cfg.Height - 2 - h
 		} else {
 			y = 2
 		}
-
-		x := int32((cfg.Width - w) / 2)
-		rect := &sdl.Rect{x, y, int32(w), int32(h)}
-		if err = g.renderer.CopyEx(texture, nil, rect, 0.0, nil, sdl.FLIP_HORIZONTAL|sdl.FLIP_VERTICAL); err != nil {
-			log.Printf("Failed to copy texture: %s\n", err)
-			return
-		}
+		x = (cfg.Width - w) / 2
+		angle = 180
 	} else if cfg.Rotate == 90 {
-		var x int32
 		if cfg.StatusTop {
-			x = int32(2 + h)
+			x = 2
 		} else {
-			x = int32(cfg.Width)
-		}
-
-		y := int32((cfg.Height - w) / 2)
-		rect := &sdl.Rect{x, y, int32(w), int32(h)}
-		if err = g.renderer.CopyEx(texture, nil, rect, 90.0, &sdl.Point{0, 0}, sdl.FLIP_HORIZONTAL|sdl.FLIP_VERTICAL); err != nil {
-			log.Printf("Failed to copy texture: %s\n", err)
-			return
+			x = cfg.Width - h
 		}
+		y = (cfg.Height - w) / 2
+		angle = 270
 	} else if cfg.Rotate == 270 {
-		var x int32
 		if cfg.StatusTop {
-			x = int32(cfg.Width)
+			x = cfg.Width - h
 		} else {
-			x = int32(2 + h)
+			x = 2
 		}
+		y = (cfg.Height - w) / 2
+		angle = 90
+	}
 
-		y := int32((cfg.Height - w) / 2)
-		rect := &sdl.Rect{x, y, int32(w), int32(h)}
-		if err = g.renderer.CopyEx(texture, nil, rect, 90.0, &sdl.Point{0, 0}, sdl.FLIP_NONE); err != nil {
-			log.Printf("Failed to copy texture: %s\n", err)
-			return
-		}
+	if err = g.canvas.DrawText(status, int32(x), int32(y), angle); err != nil {
+		log.Printf("Failed to draw text: %s\n", err)
 	}
 }
 
 // NextFrame executes the next screen of the game
 func (g *LifeGame) NextFrame() {
+	if g.rule.Range > 0 {
+		g.CountRange()
+	}
 	last := g.liveCells
 	g.liveCells = 0
+	population := make([]int, g.rule.Species+1)
+	if cfg.Noise > 0 {
+		g.startNoise()
+	}
 	for y := range g.cells {
 		for _, c := range g.cells[y] {
 			g.checkState(c)
+			if cfg.Noise > 0 {
+				g.AddNoise(c)
+			}
 			if c.aliveNext {
 				g.liveCells++
+				if g.rule.Species > 0 {
+					population[c.stateNext]++
+				}
 			}
 		}
 	}
 	if g.liveCells-last != 0 {
 		g.age++
 	}
+	g.generation++
+	g.phase = (g.phase + 1) % len(g.steps)
 
 	// Draw a new screen
-	status := fmt.Sprintf("age: %5d alive: %5d change: %5d", g.age, g.liveCells, g.liveCells-last)
-	g.Draw(status)
+	g.UpdateCells()
+	g.status = fmt.Sprintf("age: %5d alive: %5d change: %5d", g.age, g.liveCells, g.liveCells-last)
+	if g.rule.Species > 0 {
+		g.status += SpeciesStatus(population)
+	}
+	if cfg.Noise > 0 {
+		g.status += fmt.Sprintf(" noise: %g", cfg.Noise)
+	}
+	if label := g.rule.Label(); len(label) > 0 {
+		g.status += "  " + label
+	}
+	if summary := g.info.Summary(); len(summary) > 0 {
+		g.status = summary + "  " + g.status
+	}
+	if g.canvas != nil {
+		g.Draw(g.status)
+	}
+
+	g.SaveFrames()
+}
+
+// SaveFrames writes the current generation to the PNG, GIF, and video outputs
+func (g *LifeGame) SaveFrames() {
+	if cfg.PNGEvery > 0 && g.generation%int64(cfg.PNGEvery) == 0 {
+		if err := g.SavePNG(fmt.Sprintf("%s%06d.png", cfg.PNGPrefix, g.generation)); err != nil {
+			log.Printf("Failed to save PNG: %s\n", err)
+		}
+	}
+	g.RecordFrame()
+	g.WriteVideoFrame()
+}
+
+// CloseFrames finishes writing the GIF and video outputs
+func (g *LifeGame) CloseFrames() {
+	g.StopRecording()
+	if g.video != nil {
+		if err := g.video.Close(); err != nil {
+			log.Printf("Failed to close video: %s\n", err)
+		}
+		g.video = nil
+	}
 }
 
 // ShowKeysHelp prints the keys that are reconized to control behavior
-func ShowKeysHelp() {
-	fmt.Println("h           - Print help")
-	fmt.Println("<space>     - Toggle pause/play")
-	fmt.Println("c           - Toggle color")
-	fmt.Println("q           - Quit")
-	fmt.Println("s           - Single step")
-	fmt.Println("r           - Reset the game")
+func ShowKeysHelp(w io.Writer) {
+	fmt.Fprintln(w, "h           - Print help")
+	fmt.Fprintln(w, "<space>     - Toggle pause/play")
+	fmt.Fprintln(w, "c           - Toggle color")
+	fmt.Fprintln(w, "f           - Toggle fullscreen")
+	fmt.Fprintln(w, "m           - Toggle activity heat map")
+	fmt.Fprintln(w, "t           - Toggle trails")
+	fmt.Fprintln(w, "p           - Save a PNG of the world")
+	fmt.Fprintln(w, "g           - Start/Stop recording an animated GIF")
+	fmt.Fprintln(w, "w           - Write a snapshot of the game")
+	fmt.Fprintln(w, "l           - Load the snapshot")
+	fmt.Fprintln(w, "n           - Next playlist pattern")
+	fmt.Fprintln(w, "b           - Previous playlist pattern")
+	fmt.Fprintln(w, "u           - Use the next named rule")
+	fmt.Fprintln(w, "+/-         - Raise or lower the noise")
+	fmt.Fprintln(w, "q           - Quit")
+	fmt.Fprintln(w, "s           - Single step")
+	fmt.Fprintln(w, "r           - Reset the game")
 }
 
 // Run executes the main loop of the game
@@ -916,11 +1563,15 @@ func (g *LifeGame) Run() {
 			case *sdl.QuitEvent:
 				running = false
 				break
+			case *sdl.WindowEvent:
+				if t.Event == sdl.WINDOWEVENT_SIZE_CHANGED {
+					g.Resize(int(t.Data1), int(t.Data2))
+				}
 			case *sdl.KeyboardEvent:
 				if t.GetType() == sdl.KEYDOWN {
 					switch t.Keysym.Sym {
 					case sdl.K_h:
-						ShowKeysHelp()
+						ShowKeysHelp(g.out)
 					case sdl.K_q:
 						running = false
 						break
@@ -933,6 +1584,49 @@ func (g *LifeGame) Run() {
 						g.InitializeCells()
 					case sdl.K_c:
 						cfg.Color = !cfg.Color
+					case sdl.K_f:
+						g.ToggleFullscreen()
+					case sdl.K_m:
+						cfg.HeatMap = !cfg.HeatMap
+					case sdl.K_t:
+						cfg.Trails = !cfg.Trails
+					case sdl.K_p:
+						name := fmt.Sprintf("%sscreenshot-%06d.png", cfg.PNGPrefix, g.generation)
+						if err := g.SavePNG(name); err != nil {
+							log.Printf("Failed to save PNG: %s\n", err)
+						} else {
+							log.Printf("Saved %s\n", name)
+						}
+					case sdl.K_w:
+						if err := g.SaveSnapshot(cfg.Snapshot); err != nil {
+							log.Printf("Failed to save snapshot: %s\n", err)
+						} else {
+							log.Printf("Saved %s\n", cfg.Snapshot)
+						}
+					case sdl.K_l:
+						if err := g.LoadSnapshot(cfg.Snapshot); err != nil {
+							log.Printf("Failed to load snapshot: %s\n", err)
+						}
+					case sdl.K_n:
+						g.SkipPlaylist(1)
+					case sdl.K_b:
+						g.SkipPlaylist(-1)
+					case sdl.K_PLUS, sdl.K_EQUALS, sdl.K_KP_PLUS, sdl.K_MINUS, sdl.K_KP_MINUS:
+						g.ChangeNoise(t.Keysym.Sym != sdl.K_MINUS && t.Keysym.Sym != sdl.K_KP_MINUS)
+						log.Printf("Noise is %g\n", cfg.Noise)
+					case sdl.K_u:
+						nr := NextCatalogRule(g.rule.Name)
+						if err := g.UseRule(nr.Name); err != nil {
+							log.Printf("Failed to use rule %s: %s\n", nr.Name, err)
+						} else {
+							log.Printf("Rule is %s (%s)\n", nr.Name, nr.Rule)
+						}
+					case sdl.K_g:
+						if g.gif != nil {
+							g.StopRecording()
+						} else {
+							g.StartRecording(fmt.Sprintf("%s%06d.gif", cfg.PNGPrefix, g.generation))
+						}
 					}
 
 				}
@@ -945,23 +1639,23 @@ func (g *LifeGame) Run() {
 				}
 			case *sdl.MouseMotionEvent:
 				if t.GetType() == sdl.MOUSEMOTION {
-					fmt.Printf("Motion Event (%d): ", t.Which)
+					fmt.Fprintf(g.out, "Motion Event (%d): ", t.Which)
 					g.PrintCellDetails(t.X, t.Y)
 				}
 
 			case *sdl.MouseWheelEvent:
 				if t.GetType() == sdl.MOUSEWHEEL {
-					fmt.Printf("Wheel Event (%d): ", t.Which)
+					fmt.Fprintf(g.out, "Wheel Event (%d): ", t.Which)
 					g.PrintCellDetails(t.X, t.Y)
 				}
 
 			case *sdl.MultiGestureEvent:
 				if t.GetType() == sdl.MULTIGESTURE {
-					fmt.Printf("x=%0.2f y=%0.2f fingers=%d pinch=%0.2f rotate=%0.2f\n", t.X, t.Y, t.NumFingers, t.DDist, t.DTheta)
+					fmt.Fprintf(g.out, "x=%0.2f y=%0.2f fingers=%d pinch=%0.2f rotate=%0.2f\n", t.X, t.Y, t.NumFingers, t.DDist, t.DTheta)
 				}
 			case *sdl.TouchFingerEvent:
 				if t.GetType() == sdl.FINGERDOWN {
-					fmt.Printf("x=%02.f y=%02.f dx=%02.f dy=%0.2f pressure=%0.2f\n", t.X, t.Y, t.DX, t.DY, t.Pressure)
+					fmt.Fprintf(g.out, "x=%02.f y=%02.f dx=%02.f dy=%0.2f pressure=%0.2f\n", t.X, t.Y, t.DX, t.DY, t.Pressure)
 				}
 			}
 		}
@@ -972,50 +1666,157 @@ func (g *LifeGame) Run() {
 				g.NextFrame()
 				fpsTime = sdl.GetTicks()
 				oneStep = false
+				g.NextPlaylistEntry()
 			}
 		}
 
 		if g.pChan != nil {
 			select {
 			case pattern := <-g.pChan:
-				var err error
-				if strings.HasPrefix(pattern[0], "#Life 1.05") {
-					err = g.ParseLife105(pattern)
-				} else if isRLE(pattern) {
-					err = g.ParseRLE(pattern, 0, 0)
-				} else {
-					err = g.ParsePlaintext(pattern)
-				}
-				if err != nil {
+				if info, err := g.LoadPattern(pattern, 0, 0); err != nil {
 					log.Printf("Pattern error: %s\n", err)
+				} else if err := g.ApplyPatternRule(info); err != nil {
+					log.Printf("Pattern rule error: %s\n", err)
 				}
+			case req := <-g.sChan:
+				g.HandleSnapshotRequest(req)
+			case req := <-g.rChan:
+				g.HandleRuleRequest(req)
 			default:
 			}
 		}
 	}
+	g.CloseFrames()
+}
+
+// RunHeadless executes the game without a window
+// It runs as fast as possible for -generations, or forever if it is 0
+func (g *LifeGame) RunHeadless() {
+	var total int64
+	for cfg.Generations == 0 || total < cfg.Generations {
+		g.NextFrame()
+		g.NextPlaylistEntry()
+		total++
+	}
+	g.CloseFrames()
+	log.Printf("generation: %d age: %d alive: %d\n", g.generation, g.age, g.liveCells)
 }
 
 // CalculateWorldSize determines the most rows/columns to fit the world
+// The world always has at least 1 row and column, even if the window is too small for it.
 func (g *LifeGame) CalculateWorldSize() {
 	if cfg.Rotate == 0 || cfg.Rotate == 180 {
 		// The status text is subtracted from the height
 		g.columns = cfg.Width / cfg.CellSize
-		g.rows = (cfg.Height - 4 - g.font.Height()) / cfg.CellSize
+		g.rows = (cfg.Height - g.StatusHeight()) / cfg.CellSize
 	} else if cfg.Rotate == 90 || cfg.Rotate == 270 {
 		// The status text is subtracted from the width
-		g.columns = (cfg.Width - 4 - g.font.Height()) / cfg.CellSize
+		g.columns = (cfg.Width - g.StatusHeight()) / cfg.CellSize
 		g.rows = cfg.Height / cfg.CellSize
 	} else {
 		log.Fatal("Unsupported rotate value")
 	}
+	if g.columns < 1 {
+		g.columns = 1
+	}
+	if g.rows < 1 {
+		g.rows = 1
+	}
+
+	log.Printf("World is %d columns x %d rows\n", g.columns, g.rows)
+}
+
+// FitCellSize returns the largest cell size that fits the current world into the window
+// It returns 0 if the world cannot fit, even with 1 pixel cells.
+func (g *LifeGame) FitCellSize() int {
+	width, height := cfg.Width, cfg.Height
+	if cfg.Rotate == 0 || cfg.Rotate == 180 {
+		height -= g.StatusHeight()
+	} else {
+		width -= g.StatusHeight()
+	}
+	size := width / g.columns
+	if height/g.rows < size {
+		size = height / g.rows
+	}
+	return size
+}
+
+// ResizeCells moves the cells into a world of the current rows and columns
+// The old world is centered in the new one, cells that no longer fit are dropped.
+func (g *LifeGame) ResizeCells(oldColumns, oldRows int) {
+	old := g.cells
+	g.ClearCells()
+
+	dx := (g.columns - oldColumns) / 2
+	dy := (g.rows - oldRows) / 2
+	g.liveCells = 0
+	for y := range old {
+		for _, c := range old[y] {
+			x, y := c.x+dx, c.y+dy
+			if x < 0 || x >= g.columns || y < 0 || y >= g.rows {
+				continue
+			}
+			cell := *c
+			cell.x, cell.y = x, y
+			g.cells[y][x] = &cell
+			if cell.alive {
+				g.liveCells++
+			}
+		}
+	}
+}
+
+// Resize handles a change in the window size
+// Depending on -resize it either changes the size of the world or the size of the cells
+func (g *LifeGame) Resize(width, height int) {
+	if width == cfg.Width && height == cfg.Height {
+		return
+	}
+	cfg.Width, cfg.Height = width, height
+
+	if cfg.ResizeMode == "scale" {
+		if size := g.FitCellSize(); size > 0 {
+			cfg.CellSize = size
+		}
+	}
 
-	fmt.Printf("World is %d columns x %d rows\n", g.columns, g.rows)
+	columns, rows := g.columns, g.rows
+	g.CalculateWorldSize()
+	if g.columns != columns || g.rows != rows {
+		g.ResizeCells(columns, rows)
+	}
+
+	g.Draw(g.status)
+}
+
+// SetMinimumSize stops the window from being made smaller than one cell and the status text
+func (g *LifeGame) SetMinimumSize() {
+	width, height := cfg.CellSize, cfg.CellSize
+	if cfg.Rotate == 90 || cfg.Rotate == 270 {
+		width += g.StatusHeight()
+	} else {
+		height += g.StatusHeight()
+	}
+	g.window.SetMinimumSize(int32(width), int32(height))
+}
+
+// ToggleFullscreen switches between a fullscreen and a normal window
+// The window will send a resize event when it changes.
+func (g *LifeGame) ToggleFullscreen() {
+	var flags uint32
+	if g.window.GetFlags()&sdl.WINDOW_FULLSCREEN_DESKTOP == 0 {
+		flags = sdl.WINDOW_FULLSCREEN_DESKTOP
+	}
+	if err := g.window.SetFullscreen(flags); err != nil {
+		log.Printf("Failed to toggle fullscreen: %s\n", err)
+	}
 }
 
 // InitializeGame sets up the game struct and the SDL library
 // It also creates the main window
 func InitializeGame() *LifeGame {
-	game := &LifeGame{}
+	game := &LifeGame{out: ConsoleWriter()}
 
 	var err error
 	if err = sdl.Init(sdl.INIT_EVERYTHING); err != nil {
@@ -1037,13 +1838,17 @@ func InitializeGame() *LifeGame {
 	game.font.SetHinting(ttf.HINTING_NORMAL)
 	game.font.SetKerning(true)
 
+	var flags uint32 = sdl.WINDOW_SHOWN | sdl.WINDOW_RESIZABLE
+	if cfg.Fullscreen {
+		flags |= sdl.WINDOW_FULLSCREEN_DESKTOP
+	}
 	game.window, err = sdl.CreateWindow(
 		"Conway's Game of Life",
 		sdl.WINDOWPOS_UNDEFINED,
 		sdl.WINDOWPOS_UNDEFINED,
 		int32(cfg.Width),
 		int32(cfg.Height),
-		sdl.WINDOW_SHOWN)
+		flags)
 	if err != nil {
 		log.Fatalf("Problem initializing SDL window: %s", err)
 	}
@@ -1052,13 +1857,38 @@ func InitializeGame() *LifeGame {
 	if err != nil {
 		log.Fatalf("Problem initializing SDL renderer: %s", err)
 	}
+	game.canvas = NewSDLCanvas(game.renderer, game.font)
+	game.SetMinimumSize()
 
-	// White on Black background
-	game.bg = RGBAColor{0, 0, 0, 255}
-	game.fg = RGBAColor{255, 255, 255, 255}
+	// Fullscreen uses the size of the display, not the requested size
+	if cfg.Fullscreen {
+		w, h, err := game.renderer.GetOutputSize()
+		if err != nil {
+			log.Fatalf("Problem getting the fullscreen size: %s", err)
+		}
+		cfg.Width, cfg.Height = int(w), int(h)
+	}
 
 	// Calculate the number of rows and columns that will fit
 	game.CalculateWorldSize()
+	game.InitializeColors()
+
+	game.texture = cfg.Texture
+
+	return game
+}
+
+// InitializeColors sets up the cell colors and the age gradient
+func (g *LifeGame) InitializeColors() {
+	// White on Black background
+	g.bg = RGBAColor{0, 0, 0, 255}
+	g.fg = RGBAColor{255, 255, 255, 255}
+
+	trail, err := ParseColorTriplets(cfg.TrailColor)
+	if err != nil {
+		log.Fatalf("Problem parsing trail color: %s", err)
+	}
+	g.trail = trail[0]
 
 	// Parse the hex triplets
 	colors, err := ParseColorTriplets(cfg.Colors)
@@ -1069,18 +1899,26 @@ func InitializeGame() *LifeGame {
 	// Build the color gradient
 	switch cfg.Gradient {
 	case LinearGradient:
-		game.gradient, err = NewLinearGradient(colors, cfg.MaxAge)
+		g.gradient, err = NewLinearGradient(colors, cfg.MaxAge)
 		if err != nil {
 			log.Fatalf("ERROR: %s", err)
 		}
 	case PolylinearGradient:
-		game.gradient, err = NewPolylinearGradient(colors, cfg.MaxAge)
+		g.gradient, err = NewPolylinearGradient(colors, cfg.MaxAge)
 		if err != nil {
 			log.Fatalf("ERROR: %s", err)
 		}
 	case BezierGradient:
-		game.gradient = NewBezierGradient(colors, cfg.MaxAge)
+		g.gradient = NewBezierGradient(colors, cfg.MaxAge)
 	}
+}
+
+// InitializeHeadlessGame sets up the game struct without using SDL
+// The whole window size is used for the world since there is no status text.
+func InitializeHeadlessGame() *LifeGame {
+	game := &LifeGame{out: ConsoleWriter()}
+	game.CalculateWorldSize()
+	game.InitializeColors()
 
 	return game
 }
@@ -1099,6 +1937,9 @@ func ParseColorTriplets(s string) ([]RGBAColor, error) {
 	var colors []RGBAColor
 	// Convert the tuples into RGBAColor
 	for _, c := range strings.Split(s, ",") {
+		if len(c) < 6 {
+			return colors, fmt.Errorf("%q is not a hex triplet", c)
+		}
 		r, err := strconv.ParseUint(c[:2], 16, 8)
 		if err != nil {
 			return colors, err
@@ -1118,83 +1959,196 @@ func ParseColorTriplets(s string) ([]RGBAColor, error) {
 	return colors, nil
 }
 
-// Parse digits into a map of ints from 0-9
+// Parse digits into a map of ints from 0-size
 //
-// Returns an error if they aren't digits, or if there are more than 10 of them
-func parseDigits(digits string) (map[int]bool, error) {
+// Returns an error if they aren't digits, or if one is larger than the neighborhood size
+func parseDigits(digits string, size int) (map[int]bool, error) {
 	ruleMap := make(map[int]bool, 10)
 
-	var errors bool
-	var err error
-	var value int
-	if len(digits) > 10 {
-		log.Printf("%d has more than 10 digits", value)
-		errors = true
-	}
-	if value, err = strconv.Atoi(digits); err != nil {
-		log.Printf("%s must be digits from 0-9\n", digits)
-		errors = true
-	}
-	if errors {
-		return nil, fmt.Errorf("ERROR: Problem parsing digits")
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return nil, fmt.Errorf("%q must be digits from 0-%d", digits, size)
+		}
+		n := int(c - '0')
+		if n > size {
+			return nil, fmt.Errorf("%d is larger than the neighborhood size of %d", n, size)
+		}
+		// Add the digits to the map (order doesn't matter)
+		ruleMap[n] = true
 	}
 
-	// Add the digits to the map (order doesn't matter)
-	for value > 0 {
-		ruleMap[value%10] = true
-		value = value / 10
+	return ruleMap, nil
+}
+
+// formatDigits returns the counts in the map as a string of digits, in order
+func formatDigits(counts map[int]bool) string {
+	var digits []byte
+	for n := 0; n <= 9; n++ {
+		if counts[n] {
+			digits = append(digits, byte('0'+n))
+		}
 	}
+	return string(digits)
+}
 
-	return ruleMap, nil
+// Neighborhood lists the x, y offsets of the cells that are counted as neighbors
+type Neighborhood [][2]int
+
+var (
+	// MooreNeighborhood is the 8 cells surrounding a cell
+	MooreNeighborhood = Neighborhood{{-1, -1}, {0, -1}, {1, -1}, {-1, 0}, {1, 0}, {-1, 1}, {0, 1}, {1, 1}}
+	// HexNeighborhood is the 6 cells around a cell when the rows are sheared into hexagons
+	HexNeighborhood = Neighborhood{{-1, -1}, {0, -1}, {-1, 0}, {1, 0}, {0, 1}, {1, 1}}
+	// VonNeumannNeighborhood is the 4 cells above, below, left, and right of a cell
+	VonNeumannNeighborhood = Neighborhood{{0, -1}, {-1, 0}, {1, 0}, {0, 1}}
+)
+
+// Rule holds a parsed rulestring, or a rule from a rule file
+type Rule struct {
+	Name         string       // Name of the rule file's rule
+	Birth        map[int]bool // Number of neighbors to birth a new cell
+	StayAlive    map[int]bool // Number of neighbors for a cell to stay alive
+	Neighborhood Neighborhood // Cells that are counted as neighbors
+	Hex          bool         // The neighborhood is hexagonal
+	Range        int          // Range of a Larger than Life rule, 0 uses Neighborhood
+	Middle       bool         // Larger than Life rule counts the cell itself
+	Shape        byte         // Larger than Life neighborhood, M, N, or C
+	Rulestring   string       // Canonical rulestring, empty for rule files
+	Map          []bool       // Lookup table of a MAP rule, indexed by the cell and its neighbors
+	Table        *RuleTable   // Multi-state rule table, used instead of Birth and StayAlive
+	Species      int          // Number of species, for rules like Immigration, or 0
 }
 
-// ParseRulestring parses the rules that control the game
-//
-// Rulestrings are of the form Bn.../Sn... which list the number of neighbors to birth a new one,
-// and the number of neighbors to stay alive.
-func ParseRulestring(rule string) (birth map[int]bool, stayAlive map[int]bool, e error) {
-	var errors bool
+// Label returns the rule's name, or its rulestring shortened to fit in the status bar
+func (r Rule) Label() string {
+	if len(r.Name) > 0 {
+		return r.Name
+	}
+	if len(r.Rulestring) > maxRuleLabel {
+		return r.Rulestring[:maxRuleLabel-3] + "..."
+	}
+	return r.Rulestring
+}
+
+// RuleStep holds the neighbor counts used for one generation
+type RuleStep struct {
+	Birth     map[int]bool
+	StayAlive map[int]bool
+	Map       []bool // Lookup table for MAP rules, instead of Birth and StayAlive
+}
 
-	// Make sure the rule starts with a B
-	if !strings.HasPrefix(rule, "B") {
-		log.Println("ERROR: Rule must start with a 'B'")
-		errors = true
+// Size returns the number of cells that are counted as neighbors
+func (r Rule) Size() int {
+	if r.Range > 0 {
+		size := len(ltlOffsets(r.Range, r.Shape))
+		if r.Middle {
+			size++
+		}
+		return size
 	}
+	return len(r.Neighborhood)
+}
 
-	// Make sure the rule has a /S in it
-	if !strings.Contains(rule, "/S") {
-		log.Println("ERROR: Rule must contain /S")
-		errors = true
+// Steps returns the neighbor counts to use for each generation, they are used in turn
+//
+// A B0 rule births every dead cell with no live neighbors, turning the whole background
+// on. Instead of that the background is kept off, and the cells are inverted by the rule.
+// Without S8 (or the neighborhood's size) the background would flash on and off, which
+// needs two rules, the first inverts the cells and the second inverts them back. With S8
+// the background would stay on, so one rule keeps the cells inverted.
+func (r Rule) Steps() []RuleStep {
+	if r.Map != nil {
+		return r.mapSteps()
+	}
+	if !r.Birth[0] {
+		return []RuleStep{{Birth: r.Birth, StayAlive: r.StayAlive}}
+	}
+	size := r.Size()
+	// Counts not in the list
+	not := func(counts map[int]bool) map[int]bool {
+		m := make(map[int]bool)
+		for n := 0; n <= size; n++ {
+			if !counts[n] {
+				m[n] = true
+			}
+		}
+		return m
+	}
+	// Counts of the dead neighbors instead of the live ones
+	flip := func(counts map[int]bool) map[int]bool {
+		m := make(map[int]bool)
+		for n := 0; n <= size; n++ {
+			if counts[size-n] {
+				m[n] = true
+			}
+		}
+		return m
+	}
+	if r.StayAlive[size] {
+		return []RuleStep{{Birth: flip(not(r.StayAlive)), StayAlive: flip(not(r.Birth))}}
 	}
-	if errors {
-		return nil, nil, fmt.Errorf("The Rule string should look similar to: B2/S23")
+	return []RuleStep{
+		{Birth: not(r.Birth), StayAlive: not(r.StayAlive)},
+		{Birth: flip(r.StayAlive), StayAlive: flip(r.Birth)},
 	}
+}
 
-	// Split on the / returning 2 results like Bnn and Snn
-	fields := strings.Split(rule, "/")
-	if len(fields) != 2 {
-		return nil, nil, fmt.Errorf("ERROR: Problem splitting rule on /")
+// ParseRulestring parses the rules that control the game
+//
+// Rulestrings are of the form Bn.../Sn... which list the number of neighbors to birth a new one,
+// and the number of neighbors to stay alive. They can also be written in lowercase, as Sn.../Bn...,
+// without the /, or as n.../n... with the stay alive numbers first. An H suffix, eg. B2/S34H, uses
+// the hexagonal neighborhood and a V suffix uses the von Neumann neighborhood instead of the 8
+// surrounding cells. Rules starting with R are Larger than Life rules, see ParseLtL, and rules
+// starting with MAP are lookup tables, see ParseMap.
+//
+// The rule's Rulestring is set to the canonical form, eg. B3/S23.
+func ParseRulestring(rule string) (Rule, error) {
+	rule = strings.TrimSpace(rule)
+	if strings.HasPrefix(rule, "MAP") {
+		return ParseMap(rule)
+	}
+	if strings.HasPrefix(rule, "R") {
+		return ParseLtL(rule)
+	}
+
+	r := Rule{Neighborhood: MooreNeighborhood}
+	bs := strings.ToUpper(rule)
+	var suffix string
+	if strings.HasSuffix(bs, "H") {
+		r.Neighborhood = HexNeighborhood
+		r.Hex = true
+		suffix = "H"
+	} else if strings.HasSuffix(bs, "V") {
+		r.Neighborhood = VonNeumannNeighborhood
+		suffix = "V"
+	}
+	bs = strings.TrimSuffix(bs, suffix)
+
+	var birth, stayAlive string
+	if m := rulestringRegex.FindStringSubmatch(bs); m != nil {
+		birth, stayAlive = m[1]+m[4], m[2]+m[3]
+	} else if m := survivalBirthRegex.FindStringSubmatch(bs); m != nil {
+		stayAlive, birth = m[1], m[2]
+	} else {
+		return r, fmt.Errorf("Rule %q should look like B3/S23, S23/B3, B3S23, or 23/3", rule)
 	}
 
 	var err error
 	// Convert the values to maps
-	birth, err = parseDigits(strings.TrimPrefix(fields[0], "B"))
-	if err != nil {
-		errors = true
+	if r.Birth, err = parseDigits(birth, len(r.Neighborhood)); err != nil {
+		return r, fmt.Errorf("Rule %q birth: %s", rule, err)
 	}
-	stayAlive, err = parseDigits(strings.TrimPrefix(fields[1], "S"))
-	if err != nil {
-		errors = true
-	}
-	if errors {
-		return nil, nil, fmt.Errorf("ERROR: Problem with Birth or Stay alive values")
+	if r.StayAlive, err = parseDigits(stayAlive, len(r.Neighborhood)); err != nil {
+		return r, fmt.Errorf("Rule %q stay alive: %s", rule, err)
 	}
+	r.Rulestring = fmt.Sprintf("B%s/S%s%s", formatDigits(r.Birth), formatDigits(r.StayAlive), suffix)
 
-	return birth, stayAlive, nil
+	return r, nil
 }
 
 // Server starts an API server to receive patterns
-func Server(host string, port int, pChan chan<- Pattern) {
+func Server(host string, port int, pChan chan<- Pattern, sChan chan<- SnapshotRequest, rChan chan<- RuleRequest) {
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
@@ -1202,11 +2156,17 @@ func Server(host string, port int, pChan chan<- Pattern) {
 			return
 		}
 
+		r.Body = http.MaxBytesReader(w, r.Body, maxPatternBytes)
 		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(make([]byte, 4096), maxPatternBytes)
 		var pattern Pattern
 		for scanner.Scan() {
 			pattern = append(pattern, scanner.Text())
 		}
+		if err := scanner.Err(); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
 		if len(pattern) == 0 {
 			http.Error(w, "Empty pattern", http.StatusServiceUnavailable)
 			return
@@ -1215,6 +2175,9 @@ func Server(host string, port int, pChan chan<- Pattern) {
 		// Splat this pattern onto the world
 		pChan <- pattern
 	})
+	http.HandleFunc("/snapshot", snapshotHandler(sChan))
+	http.HandleFunc("/rule", ruleHandler(rChan))
+	http.HandleFunc("/rules", rulesHandler)
 
 	log.Printf("Starting server on %s:%d", host, port)
 	log.Fatal(http.ListenAndServe(fmt.Sprintf("%s:%d", host, port), nil))
@@ -1223,6 +2186,55 @@ func Server(host string, port int, pChan chan<- Pattern) {
 func main() {
 	parseArgs()
 
+	if cfg.ListRules {
+		if err := ListRules(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if cfg.PrintMap {
+		rule, err := LookupRule(cfg.Rule)
+		if err != nil {
+			log.Fatal(err)
+		}
+		m, err := rule.MapString()
+		if err != nil {
+			log.Fatalf("-rule %s: %s", cfg.Rule, err)
+		}
+		fmt.Println(m)
+		return
+	}
+
+	var video io.WriteCloser
+	if len(cfg.Y4M) > 0 {
+		var err error
+		if video, err = OpenY4M(cfg.Y4M); err != nil {
+			log.Fatalf("Failed to open video: %s", err)
+		}
+	}
+
+	if cfg.Headless {
+		game := InitializeHeadlessGame()
+		if video != nil {
+			if err := game.StartVideo(video); err != nil {
+				log.Fatalf("Failed to start video: %s", err)
+			}
+		}
+		game.InitializeCells()
+		if len(cfg.Resume) > 0 {
+			if err := game.LoadSnapshot(cfg.Resume); err != nil {
+				log.Fatalf("Failed to resume from %s: %s", cfg.Resume, err)
+			}
+		}
+		if len(cfg.Playlist) > 0 {
+			if err := game.StartPlaylist(cfg.Playlist); err != nil {
+				log.Fatalf("Failed to start playlist: %s", err)
+			}
+		}
+		game.RunHeadless()
+		return
+	}
+
 	// If the user didn't specify a font, try to find a default one
 	if len(cfg.Font) == 0 {
 		for _, f := range defaultFonts {
@@ -1241,19 +2253,38 @@ func main() {
 	game := InitializeGame()
 	defer game.cleanup()
 
+	if video != nil {
+		if err := game.StartVideo(video); err != nil {
+			log.Fatalf("Failed to start video: %s", err)
+		}
+	}
+
 	// TODO
-	// * resize events?
 	// * add a status bar (either add to height, or subtract from it)
 
 	// Setup the initial state of the world
 	game.InitializeCells()
+	if len(cfg.Resume) > 0 {
+		if err := game.LoadSnapshot(cfg.Resume); err != nil {
+			log.Fatalf("Failed to resume from %s: %s", cfg.Resume, err)
+		}
+	}
+	if len(cfg.Playlist) > 0 {
+		if err := game.StartPlaylist(cfg.Playlist); err != nil {
+			log.Fatalf("Failed to start playlist: %s", err)
+		}
+	}
 
-	ShowKeysHelp()
+	ShowKeysHelp(game.out)
 
 	if cfg.Server {
 		ch := make(chan Pattern, 2)
 		game.pChan = ch
-		go Server(cfg.Host, cfg.Port, ch)
+		sch := make(chan SnapshotRequest)
+		game.sChan = sch
+		rch := make(chan RuleRequest)
+		game.rChan = rch
+		go Server(cfg.Host, cfg.Port, ch, sch, rch)
 	}
 
 	game.Run()
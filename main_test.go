@@ -1,7 +1,9 @@
 package main
 
 import (
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -58,3 +60,724 @@ func TestFactorialCache(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateHeadless(t *testing.T) {
+	saved := keepConfig(t)
+
+	var matrix = []struct {
+		headless    bool
+		generations int64
+		pngEvery    int
+		gif         string
+		y4m         string
+		err         bool
+	}{
+		{false, 0, 0, "", "", false},
+		{true, 0, 0, "", "", true},
+		{true, 100, 0, "", "", false},
+		{true, 0, 10, "", "", false},
+		{true, 0, 0, "life.gif", "", false},
+		{true, 0, 0, "", "-", false},
+	}
+
+	for _, tt := range matrix {
+		cfg = saved
+		cfg.Headless, cfg.Generations, cfg.PNGEvery, cfg.RecordGIF, cfg.Y4M = tt.headless, tt.generations, tt.pngEvery, tt.gif, tt.y4m
+		err := validateArgs()
+		if (err != nil) != tt.err {
+			t.Errorf("%+v: expected error %v, got %v", tt, tt.err, err)
+		}
+	}
+}
+
+func TestResizeCells(t *testing.T) {
+	g := newTestGame(4, 4)
+	g.SetCellState(0, 0, true)
+	g.SetCellState(2, 1, true)
+	g.cells[1][2].age = 5
+
+	// Grow the world, the cells move to stay centered
+	g.columns, g.rows = 8, 6
+	g.ResizeCells(4, 4)
+	if len(g.cells) != 6 || len(g.cells[0]) != 8 {
+		t.Fatalf("wrong world size: %d rows x %d columns", len(g.cells), len(g.cells[0]))
+	}
+	if !g.cells[1][2].alive || !g.cells[2][4].alive || g.cells[2][4].age != 5 || g.liveCells != 2 {
+		t.Errorf("cells were not moved correctly")
+	}
+	if g.cells[2][4].x != 4 || g.cells[2][4].y != 2 {
+		t.Errorf("cell coordinates were not updated: %d, %d", g.cells[2][4].x, g.cells[2][4].y)
+	}
+
+	// Shrink it, dropping the cell at the edge
+	g.columns, g.rows = 4, 2
+	g.ResizeCells(8, 6)
+	if len(g.cells) != 2 || len(g.cells[0]) != 4 {
+		t.Fatalf("wrong world size: %d rows x %d columns", len(g.cells), len(g.cells[0]))
+	}
+	if !g.cells[0][2].alive || g.liveCells != 1 {
+		t.Errorf("cells were not moved correctly")
+	}
+
+	// A window too small for a single cell still leaves a 1 cell world
+	keepConfig(t)
+	for _, mode := range []string{"world", "scale"} {
+		cfg.Width, cfg.Height, cfg.CellSize, cfg.ResizeMode = 64, 48, 4, mode
+		g = newTestGame(16, 12)
+		g.canvas = NewImageCanvas(cfg.Width, cfg.Height)
+		g.SetRule(Rule{Birth: map[int]bool{3: true}, StayAlive: map[int]bool{2: true, 3: true}, Neighborhood: MooreNeighborhood})
+		g.Resize(2, 2)
+		if g.columns != 1 || g.rows != 1 || len(g.cells) != 1 || len(g.cells[0]) != 1 {
+			t.Fatalf("%s: expected a 1x1 world, got %d columns x %d rows", mode, g.columns, g.rows)
+		}
+		g.NextFrame()
+	}
+}
+
+func TestPatternInfo(t *testing.T) {
+	keepConfig(t)
+
+	var matrix = []struct {
+		lines   []string
+		info    PatternInfo
+		summary string
+	}{
+		{
+			[]string{"#N Gosper glider gun", "#O Bill Gosper", "#C The first known gun", "#C Found in 1970",
+				"x = 3, y = 3, rule = B3/S23", "bo$2bo$3o!"},
+			PatternInfo{Name: "Gosper glider gun", Author: "Bill Gosper", Comments: []string{"The first known gun", "Found in 1970"}, Rule: "B3/S23"},
+			"Gosper glider gun by Bill Gosper",
+		},
+		{
+			[]string{"#Life 1.05", "#D Glider", "#D Moves diagonally", "#N", "#P -1 -1", ".*", "..*", "***"},
+			PatternInfo{Comments: []string{"Glider", "Moves diagonally"}, Rule: "B3/S23"},
+			"Glider",
+		},
+		{
+			[]string{"!Name: Glider", "!Author: Richard K. Guy", "!", "!The smallest spaceship", ".O", "..O", "OOO"},
+			PatternInfo{Name: "Glider", Author: "Richard K. Guy", Comments: []string{"The smallest spaceship"}},
+			"Glider by Richard K. Guy",
+		},
+		{
+			[]string{".O", "..O", "OOO"},
+			PatternInfo{},
+			"",
+		},
+	}
+
+	for _, tt := range matrix {
+		g := newTestGame(10, 10)
+		info, err := g.LoadPattern(tt.lines, 0, 0)
+		if err != nil {
+			t.Fatalf("%v: %s", tt.lines, err)
+		}
+		if !reflect.DeepEqual(info, tt.info) {
+			t.Errorf("expected %#v, got %#v", tt.info, info)
+		}
+		if info.Summary() != tt.summary {
+			t.Errorf("expected summary %q, got %q", tt.summary, info.Summary())
+		}
+	}
+}
+
+func TestRLEPosition(t *testing.T) {
+	var matrix = []struct {
+		lines []string
+		x, y  int
+		gen   int64
+		rule  string
+	}{
+		// Without a position the pattern is centered
+		{[]string{"x = 3, y = 3", "bo$2bo$3o!"}, 3, 3, 0, ""},
+		{[]string{"#P -4 2", "x = 3, y = 3", "bo$2bo$3o!"}, 0, 6, 0, ""},
+		{[]string{"#R 1 -3", "x = 3, y = 3", "bo$2bo$3o!"}, 5, 1, 0, ""},
+		{[]string{"#CXRLE Pos=-2,-1 Gen=3", "x = 3, y = 3", "bo$2bo$3o!"}, 2, 3, 3, ""},
+		{[]string{"#r 23/3", "x = 3, y = 3", "bo$2bo$3o!"}, 3, 3, 0, "B3/S23"},
+		{[]string{"#r 23/36", "x = 3, y = 3", "bo$2bo$3o!"}, 3, 3, 0, "B36/S23"},
+		{[]string{"#r 23/3", "x = 3, y = 3, rule = B36/S23", "bo$2bo$3o!"}, 3, 3, 0, "B36/S23"},
+	}
+
+	for _, tt := range matrix {
+		g := newTestGame(8, 8)
+		info, err := g.LoadPattern(tt.lines, 0, 0)
+		if err != nil {
+			t.Fatalf("%v: %s", tt.lines, err)
+		}
+		// The top left cell of the glider's bounding box is dead, the one to its right is alive
+		if g.cells[tt.y][tt.x].alive || !g.cells[tt.y][tt.x+1].alive {
+			t.Errorf("%v: pattern is not at %d, %d", tt.lines, tt.x, tt.y)
+		}
+		if info.Gen != tt.gen {
+			t.Errorf("%v: expected generation %d, got %d", tt.lines, tt.gen, info.Gen)
+		}
+		if info.Rule != tt.rule {
+			t.Errorf("%v: expected rule %q, got %q", tt.lines, tt.rule, info.Rule)
+		}
+	}
+}
+
+func TestApplyPatternRule(t *testing.T) {
+	keepConfig(t)
+
+	g := newTestGame(8, 8)
+	info := PatternInfo{Rule: "B36/S23"}
+
+	cfg.Rule = "B3/S23"
+	cfg.RulePolicy = "cmdline"
+	if err := g.ApplyPatternRule(info); err != nil {
+		t.Fatal(err)
+	}
+	if g.RuleName() != "B3/S23" || g.rule.Birth[6] {
+		t.Errorf("cmdline policy used the pattern's rule")
+	}
+
+	cfg.RulePolicy = "file"
+	if err := g.ApplyPatternRule(info); err != nil {
+		t.Fatal(err)
+	}
+	if g.RuleName() != "B36/S23" || !g.rule.Birth[6] {
+		t.Errorf("file policy did not use the pattern's rule")
+	}
+
+	// -rule is not changed, and is used again for a pattern without a rule
+	if cfg.Rule != "B3/S23" {
+		t.Errorf("the pattern's rule replaced -rule: %s", cfg.Rule)
+	}
+	if err := g.ApplyPatternRule(PatternInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if g.RuleName() != "B3/S23" || g.rule.Birth[6] {
+		t.Errorf("a pattern without a rule did not use -rule: %s", g.RuleName())
+	}
+
+	// An RLE #r line is in S/B order
+	info, err := g.LoadPattern([]string{"#r 23/36", "x = 3, y = 1", "3o!"}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.ApplyPatternRule(info); err != nil {
+		t.Fatal(err)
+	}
+	if g.RuleName() != "B36/S23" || !g.rule.Birth[6] || g.rule.Birth[2] {
+		t.Errorf("#r 23/36 was not used as B36/S23: %s", g.RuleName())
+	}
+}
+
+// patternBounds returns the number of live cells and the box around them, relative to the center
+func patternBounds(g *LifeGame) (count, minX, minY, maxX, maxY int) {
+	minX, minY, maxX, maxY = g.columns, g.rows, -g.columns, -g.rows
+	for y := range g.cells {
+		for x, c := range g.cells[y] {
+			if !c.alive {
+				continue
+			}
+			count++
+			x, y := x-g.columns/2, y-g.rows/2
+			if x < minX {
+				minX = x
+			}
+			if x > maxX {
+				maxX = x
+			}
+			if y < minY {
+				minY = y
+			}
+			if y > maxY {
+				maxY = y
+			}
+		}
+	}
+	return
+}
+
+func TestExamples(t *testing.T) {
+	var matrix = []struct {
+		file       string
+		count      int
+		minX, minY int
+		maxX, maxY int
+		summary    string
+		rule       string
+	}{
+		{"conway.life", 21, 0, 0, 6, 8, "https://xkcd.com/2293/", "B3/S23"},
+		{"demonoid.cells", 64, 0, 0, 17, 11, "", ""},
+		{"glider-1.05.life", 5, -1, -1, 1, 1, "This is a glider.", "B3/S23"},
+		{"glider-gun-1.05.life", 36, -18, -5, 17, 3, "Gosper glider gun", "B3/S23"},
+		{"glider-gun.cells", 36, 0, 0, 35, 8, "Gosper glider gun", ""},
+		{"glider-gun.rle", 36, -18, -4, 17, 4, "Gosper glider gun", "B3/S23"},
+		{"glider.cells", 5, 0, 0, 2, 2, "Glider", ""},
+		{"lwss.cells", 9, 0, 0, 4, 3, "", ""},
+		{"pulsar-xp3.rle", 72, -6, -6, 6, 6, "", "B3/S23"},
+		{"spaceship.cells", 119, 0, 0, 34, 18, "", ""},
+		{"wireworld-clock.rle", 17, -6, -1, 6, 1, "WireWorld clock", "WireWorld"},
+	}
+
+	files, err := filepath.Glob("examples/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(matrix) {
+		t.Errorf("expected %d examples, found %d", len(matrix), len(files))
+	}
+
+	for _, tt := range matrix {
+		lines, err := ReadPattern(filepath.Join("examples", tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := newTestGame(100, 100)
+		info, err := g.LoadPattern(lines, 0, 0)
+		if err != nil {
+			t.Errorf("%s: %s", tt.file, err)
+			continue
+		}
+		count, minX, minY, maxX, maxY := patternBounds(g)
+		if count != tt.count {
+			t.Errorf("%s: expected %d live cells, got %d", tt.file, tt.count, count)
+		}
+		if minX != tt.minX || minY != tt.minY || maxX != tt.maxX || maxY != tt.maxY {
+			t.Errorf("%s: expected %d,%d %d,%d got %d,%d %d,%d", tt.file,
+				tt.minX, tt.minY, tt.maxX, tt.maxY, minX, minY, maxX, maxY)
+		}
+		if info.Summary() != tt.summary {
+			t.Errorf("%s: expected summary %q, got %q", tt.file, tt.summary, info.Summary())
+		}
+		if info.Rule != tt.rule {
+			t.Errorf("%s: expected rule %q, got %q", tt.file, tt.rule, info.Rule)
+		}
+	}
+}
+
+func TestParseLife105(t *testing.T) {
+	var matrix = []struct {
+		lines      []string
+		count      int
+		minX, minY int
+		maxX, maxY int
+		rule       string
+	}{
+		// x comes before y
+		{[]string{"#Life 1.05", "#P 3 -2", "*"}, 1, 3, -2, 3, -2, ""},
+		// Extra whitespace
+		{[]string{"#Life 1.05", "#P   3\t -2  ", "*.  "}, 1, 3, -2, 3, -2, ""},
+		// Multiple blocks
+		{[]string{"#Life 1.05", "#P -5 -5", "**", "#P 4 2", ".*", "*"}, 4, -5, -5, 5, 3, ""},
+		{[]string{"#Life 1.05", "#N", "#P 0 0", "*"}, 1, 0, 0, 0, 0, "B3/S23"},
+		{[]string{"#Life 1.05", "#R 23/3", "#P 0 0", "*"}, 1, 0, 0, 0, 0, "B3/S23"},
+		{[]string{"#Life 1.05", "#R 01/36 HighLife variant", "#P 0 0", "*"}, 1, 0, 0, 0, 0, "B36/S01"},
+		{[]string{"#Life 1.05", "#R /2", "#P 0 0", "*"}, 1, 0, 0, 0, 0, "B2/S"},
+	}
+
+	for _, tt := range matrix {
+		g := newTestGame(20, 20)
+		info, err := g.LoadPattern(tt.lines, 0, 0)
+		if err != nil {
+			t.Errorf("%v: %s", tt.lines, err)
+			continue
+		}
+		count, minX, minY, maxX, maxY := patternBounds(g)
+		if count != tt.count || minX != tt.minX || minY != tt.minY || maxX != tt.maxX || maxY != tt.maxY {
+			t.Errorf("%v: expected %d cells at %d,%d %d,%d got %d at %d,%d %d,%d", tt.lines,
+				tt.count, tt.minX, tt.minY, tt.maxX, tt.maxY, count, minX, minY, maxX, maxY)
+		}
+		if info.Rule != tt.rule {
+			t.Errorf("%v: expected rule %q, got %q", tt.lines, tt.rule, info.Rule)
+		}
+	}
+
+	for _, lines := range [][]string{
+		{"#Life 1.05", "#P 1", "*"},
+		{"#Life 1.05", "#R 23", "#P 0 0", "*"},
+		{"#Life 1.05", "#R 2x/3", "#P 0 0", "*"},
+		{"#Life 1.05", "#P 0 0", "*o*"},
+	} {
+		g := newTestGame(20, 20)
+		if _, err := g.LoadPattern(lines, 0, 0); err == nil {
+			t.Errorf("%v: did not return an error", lines)
+		}
+	}
+}
+
+func TestParseRLELimits(t *testing.T) {
+	for _, lines := range [][]string{
+		{"x = 70000, y = 1", "o!"},
+		{"x = 3, y = 3", "99999999999999999999o!"},
+	} {
+		g := newTestGame(8, 8)
+		if _, err := g.LoadPattern(lines, 0, 0); err == nil {
+			t.Errorf("%v: did not return an error", lines)
+		}
+	}
+
+	// Runs past the header's width are ignored
+	g := newTestGame(8, 8)
+	if _, err := g.LoadPattern([]string{"", "x = 2, y = 3", "65536o2$65536o!"}, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if count, _, _, _, _ := patternBounds(g); count != 4 {
+		t.Errorf("expected 4 live cells, got %d", count)
+	}
+
+	// Long runs only fill the world once, the empty rows wrap around over the first one
+	g = newTestGame(8, 8)
+	if _, err := g.LoadPattern([]string{"x = 65536, y = 65536", "65536o$65534$65536o!"}, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if count, _, _, _, _ := patternBounds(g); count != 8 {
+		t.Errorf("expected 8 live cells, got %d", count)
+	}
+}
+
+func TestHexNeighborhood(t *testing.T) {
+	rule, err := ParseRulestring("B2/S34H")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rule.Hex || !rule.Birth[2] || !rule.StayAlive[3] || !rule.StayAlive[4] {
+		t.Fatalf("B2/S34H parsed as %#v", rule)
+	}
+
+	g := newTestGame(5, 5)
+	g.rule = rule
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 5; x++ {
+			g.SetCellState(x, y, true)
+		}
+	}
+	if count, _ := g.liveNeighbors(g.cells[2][2]); count != 6 {
+		t.Errorf("expected 6 neighbors, got %d", count)
+	}
+
+	// The top right and bottom left corners are not hexagonal neighbors
+	g.ClearCells()
+	g.SetCellState(3, 1, true)
+	g.SetCellState(1, 3, true)
+	if count, _ := g.liveNeighbors(g.cells[2][2]); count != 0 {
+		t.Errorf("expected 0 neighbors, got %d", count)
+	}
+	g.SetCellState(1, 1, true)
+	g.SetCellState(3, 3, true)
+	if count, _ := g.liveNeighbors(g.cells[2][2]); count != 2 {
+		t.Errorf("expected 2 neighbors, got %d", count)
+	}
+}
+
+func TestParseDigits(t *testing.T) {
+	var matrix = []struct {
+		digits string
+		counts []int
+		err    bool
+	}{
+		{"", nil, false},
+		{"3", []int{3}, false},
+		{"03", []int{0, 3}, false},
+		{"0", []int{0}, false},
+		{"012345678", []int{0, 1, 2, 3, 4, 5, 6, 7, 8}, false},
+		{"+3", nil, true},
+		{"-3", nil, true},
+		{"3a", nil, true},
+		{"01234567890", nil, true},
+		{"9", nil, true},
+	}
+
+	for _, tt := range matrix {
+		m, err := parseDigits(tt.digits, 8)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.digits)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", tt.digits, err)
+			continue
+		}
+		expected := make(map[int]bool)
+		for _, n := range tt.counts {
+			expected[n] = true
+		}
+		if !reflect.DeepEqual(m, expected) {
+			t.Errorf("%q: expected %v, got %v", tt.digits, expected, m)
+		}
+	}
+}
+
+// nextB0 runs one generation of a rule on cells, without any B0 emulation
+func nextB0(cells [][]bool, rule Rule) [][]bool {
+	rows, columns := len(cells), len(cells[0])
+	next := make([][]bool, rows)
+	for y := range cells {
+		next[y] = make([]bool, columns)
+		for x := range cells[y] {
+			var count int
+			for _, offset := range rule.Neighborhood {
+				if cells[((y+offset[1])%rows+rows)%rows][((x+offset[0])%columns+columns)%columns] {
+					count++
+				}
+			}
+			if cells[y][x] {
+				next[y][x] = rule.StayAlive[count]
+			} else {
+				next[y][x] = rule.Birth[count]
+			}
+		}
+	}
+	return next
+}
+
+func TestB0Rules(t *testing.T) {
+	var matrix = []struct {
+		rule  string
+		steps int
+	}{
+		{"B3/S23", 1},
+		{"B017/S1", 2},
+		{"B0123478/S01234678", 1},
+		{"B03/S238", 1},
+		{"B01/S2V", 2},
+		{"B0/S2H", 2},
+	}
+
+	for _, tt := range matrix {
+		rule, err := ParseRulestring(tt.rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := randomGame(12, 10, 3)
+		g.SetRule(rule)
+		if len(g.steps) != tt.steps {
+			t.Fatalf("%s: expected %d steps, got %d", tt.rule, tt.steps, len(g.steps))
+		}
+
+		// Run the rule on the whole world, with the background turning on. With S8 the
+		// background stays on, so the pattern starts out inverted.
+		stayOn := rule.StayAlive[rule.Size()]
+		var background bool
+		world := make([][]bool, g.rows)
+		for y := range world {
+			world[y] = make([]bool, g.columns)
+			for x := range world[y] {
+				world[y][x] = g.cells[y][x].alive
+				if rule.Birth[0] && stayOn {
+					world[y][x] = !world[y][x]
+					background = true
+				}
+			}
+		}
+
+		for i := 0; i < 10; i++ {
+			g.NextFrame()
+			world = nextB0(world, rule)
+			if rule.Birth[0] {
+				background = stayOn || !background
+			}
+			var live int
+			for y := range world {
+				for x := range world[y] {
+					if world[y][x] != background {
+						live++
+					}
+					if g.cells[y][x].alive != (world[y][x] != background) {
+						t.Fatalf("%s: generation %d cell %d, %d is different", tt.rule, i+1, x, y)
+					}
+				}
+			}
+			if g.liveCells != live {
+				t.Errorf("%s: generation %d expected %d live cells, got %d", tt.rule, i+1, live, g.liveCells)
+			}
+		}
+	}
+}
+
+func TestParseRulestring(t *testing.T) {
+	var matrix = []struct {
+		rule      string
+		canonical string
+	}{
+		{"B3/S23", "B3/S23"},
+		{"b3/s23", "B3/S23"},
+		{"S23/B3", "B3/S23"},
+		{"s23/b3", "B3/S23"},
+		{"B3S23", "B3/S23"},
+		{"S23B3", "B3/S23"},
+		{"23/3", "B3/S23"},
+		{" B63/S32 ", "B36/S23"},
+		{"B2/S", "B2/S"},
+		{"/2", "B2/S"},
+		{"B/S", "B/S"},
+		{"b2/s34h", "B2/S34H"},
+		{"2/13V", "B13/S2V"},
+		{"R5,C2,M1,S34..58,B34..45,NM", "R5,C0,M1,S34..58,B34..45,NM"},
+		{"R2,C0,M0,S1,3,4,B2,NN", "R2,C0,M0,S1,3..4,B2,NN"},
+		{lifeMap + "==", lifeMap},
+	}
+	for _, tt := range matrix {
+		r, err := ParseRulestring(tt.rule)
+		if err != nil {
+			t.Errorf("%q: %s", tt.rule, err)
+			continue
+		}
+		if r.Rulestring != tt.canonical {
+			t.Errorf("%q: expected %s, got %s", tt.rule, tt.canonical, r.Rulestring)
+		}
+	}
+
+	var errors = []struct {
+		rule string
+		err  string
+	}{
+		{"3/23/4", "should look like"},
+		{"B3", "should look like"},
+		{"B3/S23/", "should look like"},
+		{"Conway", "should look like"},
+		{"B9/S23", "birth: 9 is larger than the neighborhood size of 8"},
+		{"B3/S27H", "stay alive: 7 is larger than the neighborhood size of 6"},
+		{"B3/S5V", "stay alive: 5 is larger than the neighborhood size of 4"},
+	}
+	for _, tt := range errors {
+		_, err := ParseRulestring(tt.rule)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%q: expected an error with %q, got %v", tt.rule, tt.err, err)
+		}
+	}
+}
+
+// activityGame returns a Conway's Life game with a blinker on row 2, and a lone cell at 7, 7 that dies
+func activityGame(t *testing.T) *LifeGame {
+	g := useRule(t, newTestGame(10, 10), "B3/S23")
+	for _, xy := range [][2]int{{1, 2}, {2, 2}, {3, 2}, {7, 7}} {
+		g.SetCellState(xy[0], xy[1], true)
+	}
+	return g
+}
+
+func TestHeatColor(t *testing.T) {
+	keepConfig(t)
+	cfg.HeatDecay = 0.5
+
+	// A cell changing every generation settles at a heat of 2, the top of the gradient
+	var matrix = []struct {
+		heat  float64
+		point int
+	}{
+		{0, 0},
+		{0.4, 1},
+		{1, 4},
+		{2, 9},
+		{5, 9},
+	}
+	g := newTestGame(1, 1)
+	for _, tt := range matrix {
+		if c := g.HeatColor(tt.heat); c != g.gradient.points[tt.point] {
+			t.Errorf("heat %g: expected gradient point %d %v, got %v", tt.heat, tt.point, g.gradient.points[tt.point], c)
+		}
+	}
+}
+
+func TestHeatMap(t *testing.T) {
+	keepConfig(t)
+	cfg.HeatDecay = 0.5
+
+	// Each change of a cell adds 1 to its heat, and the heat is halved every generation
+	var matrix = []struct {
+		generation int
+		x, y       int
+		heat       float64
+	}{
+		{1, 1, 2, 1},
+		{1, 2, 1, 1},
+		{1, 2, 2, 0},
+		{1, 7, 7, 1},
+		{1, 0, 0, 0},
+		{2, 1, 2, 1.5},
+		{2, 2, 1, 1.5},
+		{2, 7, 7, 0.5},
+		{3, 1, 2, 1.75},
+		{3, 2, 2, 0},
+		{3, 7, 7, 0.25},
+	}
+	g := activityGame(t)
+	for _, tt := range matrix {
+		for g.generation < int64(tt.generation) {
+			g.NextFrame()
+		}
+		if heat := g.cells[tt.y][tt.x].heat; heat != tt.heat {
+			t.Errorf("generation %d: expected %d, %d to have heat %g, got %g", tt.generation, tt.x, tt.y, tt.heat, heat)
+		}
+	}
+
+	// Only cells above the threshold are shown
+	cfg.HeatMap = true
+	if _, ok := g.CellColor(g.cells[7][7]); !ok {
+		t.Errorf("heat %g is not shown", g.cells[7][7].heat)
+	}
+	if _, ok := g.CellColor(g.cells[0][0]); ok {
+		t.Errorf("a cell without any heat is shown")
+	}
+}
+
+func TestTrailColor(t *testing.T) {
+	keepConfig(t)
+	cfg.TrailLength = 4
+
+	// The trail fades from the trail color to the background over the trail length
+	var matrix = []struct {
+		death int
+		color RGBAColor
+	}{
+		{1, RGBAColor{255, 255, 0, 255}},
+		{2, RGBAColor{191, 191, 0, 255}},
+		{3, RGBAColor{127, 127, 0, 255}},
+		{5, RGBAColor{0, 0, 0, 255}},
+	}
+	g := newTestGame(1, 1)
+	g.trail = RGBAColor{255, 255, 0, 255}
+	for _, tt := range matrix {
+		if c := g.TrailColor(tt.death); c != tt.color {
+			t.Errorf("death %d: expected %v, got %v", tt.death, tt.color, c)
+		}
+	}
+}
+
+func TestTrails(t *testing.T) {
+	keepConfig(t)
+	cfg.TrailLength = 2
+
+	// Dead cells count the generations since they died, up to 1 past the trail length
+	var matrix = []struct {
+		generation int
+		x, y       int
+		death      int
+	}{
+		{1, 1, 2, 1},
+		{1, 2, 1, 0},
+		{1, 7, 7, 1},
+		{1, 0, 0, 0},
+		{2, 1, 2, 0},
+		{2, 2, 1, 1},
+		{2, 7, 7, 2},
+		{3, 1, 2, 1},
+		{3, 7, 7, 3},
+		{4, 1, 2, 0},
+		{4, 7, 7, 3},
+	}
+	g := activityGame(t)
+	for _, tt := range matrix {
+		for g.generation < int64(tt.generation) {
+			g.NextFrame()
+		}
+		if death := g.cells[tt.y][tt.x].death; death != tt.death {
+			t.Errorf("generation %d: expected %d, %d to have death %d, got %d", tt.generation, tt.x, tt.y, tt.death, death)
+		}
+	}
+
+	// Only cells that died within the trail length are shown
+	cfg.Trails = true
+	g.trail = RGBAColor{255, 255, 0, 255}
+	if c, ok := g.CellColor(g.cells[1][2]); !ok || c != g.trail {
+		t.Errorf("a newly dead cell is not the trail color: %v %v", c, ok)
+	}
+	if _, ok := g.CellColor(g.cells[7][7]); ok {
+		t.Errorf("a cell past the end of its trail is shown")
+	}
+}
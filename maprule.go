@@ -0,0 +1,140 @@
+// sdl2-life
+// by Brian C. Lane <bcl@brianlane.com>
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// mapSize is the number of entries in a MAP rule's lookup table, one for each state of a cell
+// and its 8 neighbors
+const mapSize = 512
+
+// ParseMap parses a MAP rule, MAP followed by the base64 encoded lookup table
+//
+// The table has a bit for each state of the cell and its 8 neighbors, set when the cell is
+// alive in the next generation. The bits are in order of the index, starting with the most
+// significant bit of the first byte. The index is made of the cells, NW=256, N=128, NE=64,
+// W=32, C=16, E=8, SW=4, S=2, SE=1. The base64 padding is optional.
+func ParseMap(rule string) (Rule, error) {
+	r := Rule{Birth: make(map[int]bool), StayAlive: make(map[int]bool), Neighborhood: MooreNeighborhood}
+	if !strings.HasPrefix(rule, "MAP") {
+		return r, fmt.Errorf("MAP rules must start with MAP")
+	}
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(rule[3:], "="))
+	if err != nil {
+		return r, fmt.Errorf("Error decoding MAP rule: %s", err)
+	}
+	if len(data) != mapSize/8 {
+		return r, fmt.Errorf("MAP rule must have %d bits, not %d", mapSize, len(data)*8)
+	}
+	r.Map = make([]bool, mapSize)
+	for i := range r.Map {
+		r.Map[i] = data[i/8]&(0x80>>uint(i%8)) != 0
+	}
+	r.Rulestring, err = r.MapString()
+	return r, err
+}
+
+// MapString returns the rule as a MAP rule
+// Only rules whose neighbors are all next to the cell can be written as a MAP rule.
+func (r Rule) MapString() (string, error) {
+	table := r.Map
+	if table == nil {
+		var err error
+		if table, err = r.mapTable(); err != nil {
+			return "", err
+		}
+	}
+	data := make([]byte, mapSize/8)
+	for i, alive := range table {
+		if alive {
+			data[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+	return "MAP" + base64.RawStdEncoding.EncodeToString(data), nil
+}
+
+// mapTable returns the lookup table for a rule that counts its neighbors
+func (r Rule) mapTable() ([]bool, error) {
+	if r.Range > 0 {
+		return nil, fmt.Errorf("Larger than Life rules cannot be written as a MAP rule")
+	}
+	if r.Table != nil || r.Species > 0 {
+		return nil, fmt.Errorf("Multi-state rules cannot be written as a MAP rule")
+	}
+	for _, xy := range r.Neighborhood {
+		if xy[0] < -1 || xy[0] > 1 || xy[1] < -1 || xy[1] > 1 {
+			return nil, fmt.Errorf("Neighbor %d,%d is outside of a MAP rule's neighborhood", xy[0], xy[1])
+		}
+	}
+	table := make([]bool, mapSize)
+	for i := range table {
+		var count int
+		for _, xy := range r.Neighborhood {
+			if i&mapBit(xy[0], xy[1]) != 0 {
+				count++
+			}
+		}
+		if i&mapBit(0, 0) != 0 {
+			table[i] = r.StayAlive[count]
+		} else {
+			table[i] = r.Birth[count]
+		}
+	}
+	return table, nil
+}
+
+// mapBit returns the bit for the cell at x, y in a MAP rule's index, 0, 0 is the cell itself
+func mapBit(x, y int) int {
+	return 1 << uint(8-(y+1)*3-(x+1))
+}
+
+// mapSteps returns the lookup tables to use for each generation, see Steps
+func (r Rule) mapSteps() []RuleStep {
+	if !r.Map[0] {
+		return []RuleStep{{Map: r.Map}}
+	}
+	// Inverting all the cells inverts all the bits of the index
+	all := mapSize - 1
+	if r.Map[all] {
+		inverted := make([]bool, mapSize)
+		for i := range inverted {
+			inverted[i] = !r.Map[all^i]
+		}
+		return []RuleStep{{Map: inverted}}
+	}
+	even := make([]bool, mapSize)
+	odd := make([]bool, mapSize)
+	for i := range even {
+		even[i] = !r.Map[i]
+		odd[i] = r.Map[all^i]
+	}
+	return []RuleStep{{Map: even}, {Map: odd}}
+}
+
+// mapNeighbors returns the MAP rule index for a cell and the average age of its live neighbors
+func (g *LifeGame) mapNeighbors(c *Cell) (int, int) {
+	var index, liveCount, ageSum int
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			// If we're at an edge, check the other side of the board.
+			x := ((c.x+dx)%g.columns + g.columns) % g.columns
+			y := ((c.y+dy)%g.rows + g.rows) % g.rows
+			if !g.cells[y][x].alive {
+				continue
+			}
+			index |= mapBit(dx, dy)
+			if dx != 0 || dy != 0 {
+				liveCount++
+				ageSum += g.cells[y][x].age
+			}
+		}
+	}
+	if liveCount > 0 {
+		return index, ageSum / liveCount
+	}
+	return index, 0
+}
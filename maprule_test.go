@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// lifeMap is Conway's Life, B3/S23, as a MAP rule
+const lifeMap = "MAPARYXfhZofugWaH7oaIDogBZofuhogOiAaIDogIAAgAAWaH7oaIDogGiA6ICAAIAAaIDogIAAgACAAIAAAAAAAA"
+
+func TestMapString(t *testing.T) {
+	rule, err := ParseRulestring("B3/S23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := rule.MapString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != lifeMap {
+		t.Errorf("expected %s, got %s", lifeMap, m)
+	}
+
+	// The padding is optional, and parsing it gives back the same rule
+	for _, s := range []string{lifeMap, lifeMap + "=="} {
+		r, err := ParseRulestring(s)
+		if err != nil {
+			t.Fatalf("%s: %s", s, err)
+		}
+		if m, err := r.MapString(); err != nil || m != lifeMap {
+			t.Errorf("%s: expected %s, got %s: %v", s, lifeMap, m, err)
+		}
+	}
+
+	if rule, err = ParseRulestring("R2,C0,M0,S2..3,B3,NM"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rule.MapString(); err == nil {
+		t.Errorf("expected an error writing a Larger than Life rule as a MAP rule")
+	}
+}
+
+func TestParseMapErrors(t *testing.T) {
+	for _, rule := range []string{
+		"MAP",
+		"MAPARYX",
+		"MAP" + strings.Repeat("A", 90),
+		"MAP!" + lifeMap[4:],
+	} {
+		if _, err := ParseRulestring(rule); err == nil {
+			t.Errorf("%s: expected an error", rule)
+		}
+	}
+}
+
+func TestMapRules(t *testing.T) {
+	// Running the MAP version of a rule should be the same as the rule itself
+	for _, s := range []string{"B3/S23", "B2/S34H", "B2/S013V", "B017/S1", "B03/S238", "B36/S23"} {
+		rule, err := ParseRulestring(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := rule.MapString()
+		if err != nil {
+			t.Fatal(err)
+		}
+		mapRule, err := ParseRulestring(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rule.Steps()) != len(mapRule.Steps()) {
+			t.Fatalf("%s: expected %d steps, got %d", s, len(rule.Steps()), len(mapRule.Steps()))
+		}
+
+		g := randomGame(12, 10, 4)
+		g.SetRule(rule)
+		mg := randomGame(12, 10, 4)
+		mg.SetRule(mapRule)
+		for i := 0; i < 10; i++ {
+			g.NextFrame()
+			mg.NextFrame()
+			for y := range g.cells {
+				for x := range g.cells[y] {
+					if g.cells[y][x].alive != mg.cells[y][x].alive {
+						t.Fatalf("%s: generation %d cell %d, %d is different", s, i+1, x, y)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// sdl2-life
+// by Brian C. Lane <bcl@brianlane.com>
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+// MetaField is one piece of information about the pattern, written into exported files
+type MetaField struct {
+	Key   string
+	Value string
+}
+
+// Metadata returns the pattern's information, its rule, and the generation
+// The rule is the canonical rulestring, or the name of the rule if it doesn't have one.
+func (g *LifeGame) Metadata() []MetaField {
+	var meta []MetaField
+	if len(g.info.Name) > 0 {
+		meta = append(meta, MetaField{"Title", g.info.Name})
+	}
+	if len(g.info.Author) > 0 {
+		meta = append(meta, MetaField{"Author", g.info.Author})
+	}
+	for _, c := range g.info.Comments {
+		meta = append(meta, MetaField{"Comment", c})
+	}
+	rule := g.rule.Rulestring
+	if len(rule) == 0 {
+		rule = g.rule.Name
+	}
+	if len(rule) == 0 {
+		rule = g.RuleName()
+	}
+	meta = append(meta, MetaField{"Rule", rule})
+	meta = append(meta, MetaField{"Generation", fmt.Sprintf("%d", g.generation)})
+	meta = append(meta, MetaField{"Software", "sdl2-life"})
+	return meta
+}
+
+// latin1 returns the text as Latin-1, characters that cannot be written in it are replaced with ?
+// NUL is also replaced, it is used to separate the keyword from the text in PNG chunks.
+func latin1(text string) []byte {
+	var b []byte
+	for _, r := range text {
+		if r == 0 || r > 0xff {
+			r = '?'
+		}
+		b = append(b, byte(r))
+	}
+	return b
+}
+
+// pngText returns the PNG data with a tEXt chunk added for each of the metadata fields
+// The chunks are placed right after the IHDR chunk.
+func pngText(data []byte, meta []MetaField) []byte {
+	// The 8 byte signature, and the 25 byte IHDR chunk
+	const ihdrEnd = 33
+	if len(data) < ihdrEnd {
+		return data
+	}
+	var chunks bytes.Buffer
+	for _, m := range meta {
+		chunk := append([]byte("tEXt"), latin1(m.Key)...)
+		chunk = append(chunk, 0)
+		chunk = append(chunk, latin1(m.Value)...)
+		binary.Write(&chunks, binary.BigEndian, uint32(len(chunk)-4))
+		chunks.Write(chunk)
+		binary.Write(&chunks, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	}
+	out := make([]byte, 0, len(data)+chunks.Len())
+	out = append(out, data[:ihdrEnd]...)
+	out = append(out, chunks.Bytes()...)
+	return append(out, data[ihdrEnd:]...)
+}
+
+// metaText returns the metadata as Key: Value lines
+func metaText(meta []MetaField) string {
+	var lines []string
+	for _, m := range meta {
+		lines = append(lines, m.Key+": "+m.Value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// gifComment returns a GIF comment extension holding the metadata
+func gifComment(meta []MetaField) []byte {
+	// The text is split into sub-blocks of up to 255 bytes, ending with an empty one
+	comment := []byte{0x21, 0xfe}
+	text := latin1(metaText(meta))
+	for len(text) > 0 {
+		n := len(text)
+		if n > 255 {
+			n = 255
+		}
+		comment = append(comment, byte(n))
+		comment = append(comment, text[:n]...)
+		text = text[n:]
+	}
+	return append(comment, 0)
+}
+
+// y4mEscaper escapes the characters that cannot be used in a Y4M header parameter
+var y4mEscaper = strings.NewReplacer("%", "%25", " ", "%20", "\n", "%0A", "\r", "%0D", "\t", "%09")
+
+// y4mParams returns the metadata as Y4M X parameters, eg. XLIFE_RULE=B3/S23
+// The values are percent escaped so that they don't contain spaces or newlines.
+func y4mParams(meta []MetaField) string {
+	var params []string
+	for _, m := range meta {
+		params = append(params, "XLIFE_"+strings.ToUpper(m.Key)+"="+y4mEscaper.Replace(m.Value))
+	}
+	return strings.Join(params, " ")
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// metaGame returns a game with pattern information to export
+func metaGame(t *testing.T) *LifeGame {
+	g := useRule(t, newTestGame(6, 4), "B3/S23")
+	g.SetPatternInfo(PatternInfo{Name: "Glider", Author: "Richard K. Guy", Comments: []string{"The smallest ship", "50% off"}})
+	g.generation = 12
+	g.SetCellState(1, 1, true)
+	return g
+}
+
+// expectedMeta is the metadata for metaGame, as key: value lines
+const expectedMeta = "Title: Glider\nAuthor: Richard K. Guy\nComment: The smallest ship\nComment: 50% off\n" +
+	"Rule: B3/S23\nGeneration: 12\nSoftware: sdl2-life"
+
+// readPNGText returns the tEXt chunks of a PNG as key: value lines
+func readPNGText(t *testing.T, data []byte) string {
+	var lines []string
+	for i := 8; i+8 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		if i+12+n > len(data) {
+			t.Fatalf("truncated chunk at %d", i)
+		}
+		if string(data[i+4:i+8]) == "tEXt" {
+			kv := bytes.SplitN(data[i+8:i+8+n], []byte{0}, 2)
+			lines = append(lines, string(kv[0])+": "+string(kv[1]))
+		}
+		i += 12 + n
+	}
+	return strings.Join(lines, "\n")
+}
+
+// readGIFComment returns the first comment extension of a GIF
+func readGIFComment(t *testing.T, data []byte) string {
+	i := 13
+	if data[10]&0x80 != 0 {
+		i += 3 << (data[10]&0x07 + 1)
+	}
+	for i+2 < len(data) && data[i] == 0x21 {
+		label := data[i+1]
+		var text []byte
+		for i += 2; data[i] != 0; i += int(data[i]) + 1 {
+			text = append(text, data[i+1:i+1+int(data[i])]...)
+		}
+		i++
+		if label == 0xfe {
+			return string(text)
+		}
+	}
+	t.Fatalf("no comment before the first frame")
+	return ""
+}
+
+// readY4MParams returns the XLIFE_ parameters of a Y4M header as key: value lines
+func readY4MParams(t *testing.T, data []byte) string {
+	header := strings.SplitN(string(data), "\n", 2)[0]
+	var lines []string
+	for _, p := range strings.Fields(header) {
+		if !strings.HasPrefix(p, "XLIFE_") {
+			continue
+		}
+		kv := strings.SplitN(p[6:], "=", 2)
+		value, err := url.PathUnescape(kv[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := kv[0][:1] + strings.ToLower(kv[0][1:])
+		lines = append(lines, key+": "+value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestMetadata(t *testing.T) {
+	keepConfig(t)
+	cfg.PNGCellSize, cfg.PNGScheme, cfg.CellSize = 2, "mono", 2
+	dir := t.TempDir()
+
+	g := metaGame(t)
+	var meta []string
+	for _, m := range g.Metadata() {
+		meta = append(meta, m.Key+": "+m.Value)
+	}
+	if strings.Join(meta, "\n") != expectedMeta {
+		t.Errorf("expected metadata %q, got %q", expectedMeta, meta)
+	}
+
+	// PNG tEXt chunks
+	path := filepath.Join(dir, "test.png")
+	if err := g.SavePNG(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Errorf("PNG with text does not decode: %s", err)
+	}
+	if text := readPNGText(t, data); text != expectedMeta {
+		t.Errorf("PNG: expected %q, got %q", expectedMeta, text)
+	}
+
+	// GIF comment extension
+	path = filepath.Join(dir, "test.gif")
+	r, err := NewGIFRecorder(g, path, 2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.AddFrame(g)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = ioutil.ReadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gif.DecodeAll(bytes.NewReader(data)); err != nil {
+		t.Errorf("GIF with a comment does not decode: %s", err)
+	}
+	if text := readGIFComment(t, data); text != expectedMeta {
+		t.Errorf("GIF: expected %q, got %q", expectedMeta, text)
+	}
+
+	// Y4M header parameters
+	var buf bytes.Buffer
+	if err := g.StartVideo(nopCloser{&buf}); err != nil {
+		t.Fatal(err)
+	}
+	g.WriteVideoFrame()
+	if err := g.video.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if text := readY4MParams(t, buf.Bytes()); text != expectedMeta {
+		t.Errorf("Y4M: expected %q, got %q", expectedMeta, text)
+	}
+}
@@ -0,0 +1,62 @@
+// sdl2-life
+// by Brian C. Lane <bcl@brianlane.com>
+package main
+
+import (
+	"math/rand"
+)
+
+// minNoise is the lowest noise rate used by the noise keys, below that it turns off
+const minNoise = 0.0001
+
+// SeedNoise sets the seed for the noise's random numbers
+// The noise has its own random numbers, separate from the ones used to fill the world, so
+// that the same -seed always gives the same run.
+func (g *LifeGame) SeedNoise(seed int64) {
+	g.noiseSeed = seed
+}
+
+// startNoise starts the noise's random numbers for the next generation
+// They start from the seed and the generation, so a game restored from a snapshot adds the
+// same noise as the game that saved it.
+func (g *LifeGame) startNoise() {
+	g.rng = rand.New(rand.NewSource(g.noiseSeed ^ int64(uint64(g.generation)*0x9e3779b97f4a7c15)))
+}
+
+// AddNoise flips the cell's next state with a probability of -noise
+// A cell that is flipped on starts with an age of 1, and is in state 1 for multi-state rules,
+// or a random species for rules with species.
+func (g *LifeGame) AddNoise(c *Cell) {
+	if g.rng.Float64() >= cfg.Noise {
+		return
+	}
+	c.aliveNext = !c.aliveNext
+	if c.aliveNext {
+		c.age = 1
+		c.stateNext = 1
+		if g.rule.Species > 0 {
+			c.stateNext = uint8(g.rng.Intn(g.rule.Species) + 1)
+		}
+	} else {
+		c.age = 0
+		c.stateNext = 0
+	}
+}
+
+// ChangeNoise doubles the noise rate when up is true, or halves it
+// Raising it from 0 starts at minNoise, and lowering it below minNoise turns it off.
+func (g *LifeGame) ChangeNoise(up bool) {
+	switch {
+	case up && cfg.Noise < minNoise:
+		cfg.Noise = minNoise
+	case up:
+		cfg.Noise *= 2
+		if cfg.Noise > 1 {
+			cfg.Noise = 1
+		}
+	case cfg.Noise/2 < minNoise:
+		cfg.Noise = 0
+	default:
+		cfg.Noise /= 2
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+)
+
+// noiseGame returns a Conway's Life game with random cells and the noise seeded
+func noiseGame(t *testing.T, seed int64) *LifeGame {
+	g := useRule(t, randomGame(20, 15, 3), "B3/S23")
+	g.SeedNoise(seed)
+	return g
+}
+
+func TestNoiseSeed(t *testing.T) {
+	keepConfig(t)
+	cfg.Noise = 0.05
+
+	a := noiseGame(t, 42)
+	b := noiseGame(t, 42)
+	c := noiseGame(t, 43)
+	different := false
+	for i := 0; i < 10; i++ {
+		a.NextFrame()
+		b.NextFrame()
+		c.NextFrame()
+	}
+	for y := range a.cells {
+		for x := range a.cells[y] {
+			if a.cells[y][x].alive != b.cells[y][x].alive {
+				t.Fatalf("cell %d, %d is different with the same seed", x, y)
+			}
+			if a.cells[y][x].alive != c.cells[y][x].alive {
+				different = true
+			}
+		}
+	}
+	if !different {
+		t.Errorf("different seeds gave the same cells")
+	}
+}
+
+func TestNoiseFlip(t *testing.T) {
+	keepConfig(t)
+
+	// With a noise of 1 every cell is the opposite of what the rule gives
+	want := noiseGame(t, 1)
+	want.NextFrame()
+	cfg.Noise = 1
+	g := noiseGame(t, 1)
+	g.NextFrame()
+	for y := range g.cells {
+		for x := range g.cells[y] {
+			c := g.cells[y][x]
+			if c.alive == want.cells[y][x].alive {
+				t.Fatalf("cell %d, %d was not flipped", x, y)
+			}
+			if c.alive != (c.state != 0) {
+				t.Fatalf("cell %d, %d state %d does not match alive %v", x, y, c.state, c.alive)
+			}
+		}
+	}
+}
+
+func TestChangeNoise(t *testing.T) {
+	keepConfig(t)
+
+	var matrix = []struct {
+		noise float64
+		up    bool
+		want  float64
+	}{
+		{0, true, minNoise},
+		{minNoise, true, minNoise * 2},
+		{0.25, true, 0.5},
+		{0.75, true, 1},
+		{1, true, 1},
+		{0.5, false, 0.25},
+		{minNoise, false, 0},
+		{0, false, 0},
+	}
+	var g LifeGame
+	for _, m := range matrix {
+		cfg.Noise = m.noise
+		g.ChangeNoise(m.up)
+		if cfg.Noise != m.want {
+			t.Errorf("ChangeNoise(%v) from %g: got %g, want %g", m.up, m.noise, cfg.Noise, m.want)
+		}
+	}
+}
+
+func TestNoiseSnapshot(t *testing.T) {
+	keepConfig(t)
+	cfg.Noise = 0.05
+
+	// A game restored from a snapshot adds the same noise as the one that saved it
+	g := noiseGame(t, 42)
+	for i := 0; i < 5; i++ {
+		g.NextFrame()
+	}
+	s := g.Snapshot()
+	for i := 0; i < 5; i++ {
+		g.NextFrame()
+	}
+
+	r := noiseGame(t, 7)
+	if err := r.Restore(s); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		r.NextFrame()
+	}
+	for y := range g.cells {
+		for x := range g.cells[y] {
+			if g.cells[y][x].alive != r.cells[y][x].alive {
+				t.Fatalf("cell %d, %d is different after restoring", x, y)
+			}
+		}
+	}
+}
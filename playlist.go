@@ -0,0 +1,263 @@
+// sdl2-life
+// by Brian C. Lane <bcl@brianlane.com>
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// playlistSettings are the flags that can be changed by a playlist entry
+var playlistSettings = map[string]bool{
+	"rule":         true,
+	"fps":          true,
+	"color":        true,
+	"colors":       true,
+	"gradient":     true,
+	"age":          true,
+	"border":       true,
+	"heatmap":      true,
+	"heat-decay":   true,
+	"trails":       true,
+	"trail-length": true,
+	"trail-color":  true,
+}
+
+// PlaylistEntry is one pattern in the playlist and how to show it
+type PlaylistEntry struct {
+	Path        string
+	Generations int64             // Generations to run the pattern for, 0 uses Seconds
+	Seconds     int               // Seconds to run the pattern for
+	X, Y        int               // Offset of the pattern from its normal position
+	Settings    map[string]string // Flags to change while the pattern is running
+}
+
+// Playlist holds the patterns to cycle through and the currently running entry
+type Playlist struct {
+	entries  []PlaylistEntry
+	current  int
+	start    time.Time
+	startGen int64             // Generation the current entry started at, eg. from #CXRLE Gen=
+	saved    map[string]string // Values of the flags changed by the current entry, before it changed them
+}
+
+// restoreSettings changes the flags set by the current entry back to what they were
+// Settings that were not changed by the entry, like the window size, are left alone.
+func (p *Playlist) restoreSettings() {
+	for key, value := range p.saved {
+		if err := flag.Set(key, value); err != nil {
+			log.Printf("Failed to restore %s: %s\n", key, err)
+		}
+	}
+	p.saved = make(map[string]string)
+}
+
+// ParsePlaylistEntry parses a playlist line
+// The line is the path to the pattern followed by optional key=value settings:
+//
+//	generations=N or seconds=N  How long to show the pattern
+//	x=N y=N                     Move the pattern from its normal position
+//	rule=B36/S23 fps=20 ...     Cmdline flags to use for this pattern
+func ParsePlaylistEntry(line string) (PlaylistEntry, error) {
+	fields := strings.Fields(line)
+	entry := PlaylistEntry{Path: fields[0], Generations: cfg.PlaylistGen, Settings: make(map[string]string)}
+	for _, f := range fields[1:] {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			return entry, fmt.Errorf("expected key=value, not %q", f)
+		}
+		var err error
+		switch kv[0] {
+		case "generations":
+			entry.Generations, err = strconv.ParseInt(kv[1], 10, 64)
+			entry.Seconds = 0
+		case "seconds":
+			entry.Seconds, err = strconv.Atoi(kv[1])
+			entry.Generations = 0
+		case "x":
+			entry.X, err = strconv.Atoi(kv[1])
+		case "y":
+			entry.Y, err = strconv.Atoi(kv[1])
+		default:
+			if !playlistSettings[kv[0]] {
+				return entry, fmt.Errorf("unknown setting %q", kv[0])
+			}
+			entry.Settings[kv[0]] = kv[1]
+		}
+		if err != nil {
+			return entry, fmt.Errorf("%s: %s", kv[0], err)
+		}
+	}
+	if entry.Generations < 1 && entry.Seconds < 1 {
+		return entry, fmt.Errorf("generations or seconds must be 1 or more")
+	}
+	return entry, nil
+}
+
+// ReadPlaylist reads the playlist entries from a file, or from all the patterns in a directory
+// Relative paths in a playlist file are relative to the playlist's directory.
+func ReadPlaylist(path string) ([]PlaylistEntry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []PlaylistEntry
+	if info.IsDir() {
+		files, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+		for _, f := range files {
+			if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+				continue
+			}
+			entries = append(entries, PlaylistEntry{Path: filepath.Join(path, f.Name()), Generations: cfg.PlaylistGen})
+		}
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for n := 1; scanner.Scan(); n++ {
+			line := strings.TrimSpace(scanner.Text())
+			if len(line) == 0 || line[0] == '#' {
+				continue
+			}
+			entry, err := ParsePlaylistEntry(line)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %s", path, n, err)
+			}
+			if !filepath.IsAbs(entry.Path) {
+				entry.Path = filepath.Join(filepath.Dir(path), entry.Path)
+			}
+			entries = append(entries, entry)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s has no patterns", path)
+	}
+	return entries, nil
+}
+
+// StartPlaylist loads the playlist and shows the first pattern
+func (g *LifeGame) StartPlaylist(path string) error {
+	entries, err := ReadPlaylist(path)
+	if err != nil {
+		return err
+	}
+	g.playlist = &Playlist{entries: entries, saved: make(map[string]string)}
+	return g.ShowPlaylistEntry(0)
+}
+
+// ShowPlaylistEntry clears the world and loads the entry's pattern and settings
+// Entries that fail to load are skipped, in the direction of travel
+func (g *LifeGame) ShowPlaylistEntry(i int) error {
+	p := g.playlist
+	n := len(p.entries)
+	step := 1
+	if i < p.current {
+		step = -1
+	}
+	for tries := 0; tries < n; tries++ {
+		idx := ((i+tries*step)%n + n) % n
+		err := g.loadPlaylistEntry(p.entries[idx])
+		if err == nil {
+			p.current = idx
+			p.start = time.Now()
+			p.startGen = g.generation
+			return nil
+		}
+		log.Printf("Skipping %s: %s\n", p.entries[idx].Path, err)
+	}
+	return fmt.Errorf("none of the playlist patterns could be loaded")
+}
+
+// loadPlaylistEntry applies the entry's settings and loads its pattern
+func (g *LifeGame) loadPlaylistEntry(entry PlaylistEntry) error {
+	lines, err := ReadPattern(entry.Path)
+	if err != nil {
+		return err
+	}
+
+	// Undo the previous entry's settings before applying this entry's
+	p := g.playlist
+	p.restoreSettings()
+	for key, value := range entry.Settings {
+		p.saved[key] = flag.Lookup(key).Value.String()
+		if err := flag.Set(key, value); err != nil {
+			p.restoreSettings()
+			return fmt.Errorf("invalid value %q for %s: %s", value, key, err)
+		}
+	}
+	if err := validateArgs(); err != nil {
+		p.restoreSettings()
+		return err
+	}
+	g.InitializeColors()
+
+	g.age = 0
+	g.generation = 0
+	g.liveCells = 0
+	g.ClearCells()
+	info, err := g.LoadPattern(lines, entry.X, entry.Y)
+	if err != nil {
+		return err
+	}
+	if len(info.Summary()) == 0 {
+		info.Name = filepath.Base(entry.Path)
+	}
+	if err := g.ApplyPatternRule(info); err != nil {
+		return err
+	}
+	g.generation = info.Gen
+	log.Printf("Playing %s: %s\n", entry.Path, info.Summary())
+
+	g.UpdateCells()
+	g.status = info.Summary()
+	if g.canvas != nil {
+		g.Draw(g.status)
+	}
+	return nil
+}
+
+// NextPlaylistEntry moves to the next pattern when the current one has run long enough
+func (g *LifeGame) NextPlaylistEntry() {
+	if g.playlist == nil {
+		return
+	}
+	entry := g.playlist.entries[g.playlist.current]
+	if entry.Generations > 0 && g.generation-g.playlist.startGen < entry.Generations {
+		return
+	}
+	if entry.Seconds > 0 && time.Since(g.playlist.start) < time.Duration(entry.Seconds)*time.Second {
+		return
+	}
+	g.SkipPlaylist(1)
+}
+
+// SkipPlaylist moves forward or backwards in the playlist
+func (g *LifeGame) SkipPlaylist(n int) {
+	if g.playlist == nil {
+		return
+	}
+	if err := g.ShowPlaylistEntry(g.playlist.current + n); err != nil {
+		log.Printf("Playlist error: %s\n", err)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// testFlags makes sure the cmdline flags are defined, only once, for tests that change them
+var testFlags sync.Once
+
+func TestParsePlaylistEntry(t *testing.T) {
+	var matrix = []struct {
+		line  string
+		entry PlaylistEntry
+		err   bool
+	}{
+		{"glider.cells", PlaylistEntry{Path: "glider.cells", Generations: cfg.PlaylistGen, Settings: map[string]string{}}, false},
+		{"gun.rle  seconds=30 x=-5 y=7", PlaylistEntry{Path: "gun.rle", Seconds: 30, X: -5, Y: 7, Settings: map[string]string{}}, false},
+		{"gun.rle generations=100 rule=B36/S23 fps=20 color=true",
+			PlaylistEntry{Path: "gun.rle", Generations: 100, Settings: map[string]string{"rule": "B36/S23", "fps": "20", "color": "true"}}, false},
+		{"gun.rle seconds=0", PlaylistEntry{}, true},
+		{"gun.rle x=left", PlaylistEntry{}, true},
+		{"gun.rle font=/tmp/font.ttf", PlaylistEntry{}, true},
+		{"gun.rle rule", PlaylistEntry{}, true},
+	}
+
+	for _, tt := range matrix {
+		entry, err := ParsePlaylistEntry(tt.line)
+		if err != nil {
+			if !tt.err {
+				t.Errorf("unexpected error for %s: %s", tt.line, err)
+			}
+			continue
+		} else if tt.err {
+			t.Errorf("%s did not return an error", tt.line)
+			continue
+		}
+		if !reflect.DeepEqual(entry, tt.entry) {
+			t.Errorf("expected %#v, got %#v", tt.entry, entry)
+		}
+	}
+}
+
+func TestPlaylistSettings(t *testing.T) {
+	keepConfig(t)
+	testFlags.Do(defineFlags)
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.cells":  "!Name: A\n.O.\n..O\nOOO\n",
+		"b.cells":  "!Name: B\nOOO\n",
+		"playlist": "a.cells fps=5 trails=true\nb.cells\n",
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg.Fps, cfg.Trails = 30, false
+	g := newTestGame(16, 16)
+	if err := g.StartPlaylist(filepath.Join(dir, "playlist")); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Fps != 5 || !cfg.Trails {
+		t.Errorf("entry settings were not applied: fps %d trails %v", cfg.Fps, cfg.Trails)
+	}
+
+	// Changes made while it is running, eg. resizing the window, are kept
+	cfg.Width, cfg.CellSize, cfg.Noise = 123, 7, 0.5
+	g.SkipPlaylist(1)
+	if g.playlist.current != 1 {
+		t.Fatalf("expected the 2nd entry, got %d", g.playlist.current)
+	}
+	if cfg.Fps != 30 || cfg.Trails {
+		t.Errorf("entry settings were not restored: fps %d trails %v", cfg.Fps, cfg.Trails)
+	}
+	if cfg.Width != 123 || cfg.CellSize != 7 || cfg.Noise != 0.5 {
+		t.Errorf("runtime settings were lost: width %d cell %d noise %g", cfg.Width, cfg.CellSize, cfg.Noise)
+	}
+}
+
+func TestPlaylistRules(t *testing.T) {
+	keepConfig(t)
+	cfg.Rule, cfg.RulePolicy = "B3/S23", "file"
+
+	// A pattern's rule is only used for its own entry
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.rle":    "x = 3, y = 1, rule = B36/S23\n3o!\n",
+		"b.cells":  "!Name: B\nOOO\n",
+		"playlist": "a.rle\nb.cells\n",
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := newTestGame(16, 16)
+	if err := g.StartPlaylist(filepath.Join(dir, "playlist")); err != nil {
+		t.Fatal(err)
+	}
+	if g.RuleName() != "B36/S23" || !g.rule.Birth[6] {
+		t.Errorf("expected the pattern's rule, got %s", g.RuleName())
+	}
+	g.SkipPlaylist(1)
+	if g.RuleName() != "B3/S23" || g.rule.Birth[6] || cfg.Rule != "B3/S23" {
+		t.Errorf("expected -rule B3/S23, got %s -rule %s", g.RuleName(), cfg.Rule)
+	}
+}
+
+func TestPlaylistGenerations(t *testing.T) {
+	keepConfig(t)
+
+	// The entry runs for its generations, starting from the pattern's own generation
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.rle":    "#CXRLE Pos=0,0 Gen=1000\nx = 3, y = 1\n3o!\n",
+		"b.cells":  "!Name: B\nOOO\n",
+		"playlist": "a.rle generations=50\nb.cells\n",
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := newTestGame(16, 16)
+	if err := g.StartPlaylist(filepath.Join(dir, "playlist")); err != nil {
+		t.Fatal(err)
+	}
+	if g.generation != 1000 {
+		t.Fatalf("expected generation 1000, got %d", g.generation)
+	}
+	for i := 0; i < 49; i++ {
+		g.NextFrame()
+		g.NextPlaylistEntry()
+	}
+	if g.playlist.current != 0 {
+		t.Fatalf("moved to entry %d after %d generations", g.playlist.current, g.generation-1000)
+	}
+	g.NextFrame()
+	g.NextPlaylistEntry()
+	if g.playlist.current != 1 {
+		t.Errorf("expected the 2nd entry after 50 generations, got %d", g.playlist.current)
+	}
+}
@@ -0,0 +1,237 @@
+// sdl2-life
+// by Brian C. Lane <bcl@brianlane.com>
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxNeighborhoodRange is the furthest a custom neighbor can be from the cell
+const maxNeighborhoodRange = 10
+
+// ruleFilePath returns the path of the rule file for a rule, or an empty string if there isn't one
+// The rule can be the name of a rule in -rule-dir, or if allowPath is true the path to a .rule
+// file. Rules from patterns and the API are not allowed to be paths, so that they cannot read
+// files outside of -rule-dir.
+func ruleFilePath(rule string, allowPath bool) string {
+	path := filepath.Join(cfg.RuleDir, rule+".rule")
+	if strings.HasSuffix(rule, ".rule") {
+		path = rule
+	}
+	if !allowPath && (path == rule || strings.ContainsAny(rule, `/\`)) {
+		return ""
+	}
+	// Reading a FIFO or a device could block the game
+	if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() {
+		return path
+	}
+	return ""
+}
+
+// LookupRule returns the rule for a rulestring, the name of a rule file, or the name of a
+// rule in the catalogue. Rulestrings that are in the catalogue are given its name.
+// The rule can also be the path to a rule file, it should only be used for the -rule flag.
+func LookupRule(rule string) (Rule, error) {
+	return lookupRule(rule, true)
+}
+
+// lookupRule returns the rule, only looking for rule files outside of -rule-dir if allowPath is true
+func lookupRule(rule string, allowPath bool) (Rule, error) {
+	if path := ruleFilePath(rule, allowPath); len(path) > 0 {
+		return ReadRuleFile(path)
+	}
+	nr, named := CatalogRule(rule)
+	if named {
+		rule = nr.Rule
+	}
+	r, err := ParseRulestring(rule)
+	if err != nil {
+		return r, err
+	}
+	if species, ok := speciesRules[nr.Name]; named && ok {
+		// The rulestring doesn't have the species, so the rule is only known by its name
+		r.Name, r.Species, r.Rulestring = nr.Name, species, ""
+		return r, nil
+	}
+	r.Name = catalogName(r)
+	return r, nil
+}
+
+// ReadRuleFile reads and parses a rule file
+func ReadRuleFile(path string) (Rule, error) {
+	lines, err := ReadPattern(path)
+	if err != nil {
+		return Rule{}, err
+	}
+	r, err := ParseRuleFile(lines)
+	if err != nil {
+		return r, fmt.Errorf("%s: %s", path, err)
+	}
+	return r, nil
+}
+
+// splitSections splits a rule file into its @ sections
+// Anything on the same line as the section name is the first line of the section. Blank
+// lines, and lines starting with #, are skipped.
+func splitSections(lines []string) (map[string][]string, error) {
+	sections := make(map[string][]string)
+	var section string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		if line[0] == '@' {
+			fields := strings.SplitN(line[1:], " ", 2)
+			section = strings.ToUpper(fields[0])
+			if _, ok := sections[section]; ok {
+				return nil, fmt.Errorf("More than one @%s section", section)
+			}
+			sections[section] = nil
+			if len(fields) > 1 && len(strings.TrimSpace(fields[1])) > 0 {
+				sections[section] = append(sections[section], strings.TrimSpace(fields[1]))
+			}
+			continue
+		}
+		if len(section) == 0 {
+			return nil, fmt.Errorf("Rule file must start with @RULE")
+		}
+		sections[section] = append(sections[section], line)
+	}
+	if _, ok := sections["RULE"]; !ok {
+		return nil, fmt.Errorf("Missing @RULE section")
+	}
+	return sections, nil
+}
+
+// ParseRuleFile parses a rule with a custom neighborhood
+//
+//	@RULE name
+//	@NEIGHBORHOOD followed by a mask, or a list of x,y offsets
+//	@BIRTH numbers of neighbors that birth a new cell
+//	@SURVIVAL numbers of neighbors that keep a cell alive
+//
+// The mask is centered on the cell, with * for the cells that are counted and . for the
+// ones that are not. The center can be * to count the cell itself.
+//
+// Multi-state rules use a Golly style @TABLE section instead, with optional @COLORS,
+// see ParseRuleTable.
+func ParseRuleFile(lines []string) (Rule, error) {
+	sections, err := splitSections(lines)
+	if err != nil {
+		return Rule{}, err
+	}
+	var r Rule
+	if len(sections["RULE"]) > 0 {
+		r.Name = strings.Fields(sections["RULE"][0])[0]
+	}
+
+	if _, ok := sections["TABLE"]; ok {
+		if r.Table, err = ParseRuleTable(sections["TABLE"], sections["COLORS"]); err != nil {
+			return r, fmt.Errorf("@TABLE: %s", err)
+		}
+		r.Neighborhood = r.Table.Neighbors
+		r.Hex = len(r.Neighborhood) == len(tableHex)
+		r.Birth, r.StayAlive = make(map[int]bool), make(map[int]bool)
+		return r, nil
+	}
+	if _, ok := sections["TREE"]; ok {
+		return r, fmt.Errorf("@TREE rules are not supported, only @TABLE")
+	}
+
+	for _, name := range []string{"NEIGHBORHOOD", "BIRTH", "SURVIVAL"} {
+		if _, ok := sections[name]; !ok {
+			return r, fmt.Errorf("Missing @%s section", name)
+		}
+	}
+	if r.Neighborhood, err = parseNeighborhood(sections["NEIGHBORHOOD"]); err != nil {
+		return r, err
+	}
+	if r.Birth, err = parseCounts(sections["BIRTH"], len(r.Neighborhood)); err != nil {
+		return r, fmt.Errorf("@BIRTH: %s", err)
+	}
+	if r.StayAlive, err = parseCounts(sections["SURVIVAL"], len(r.Neighborhood)); err != nil {
+		return r, fmt.Errorf("@SURVIVAL: %s", err)
+	}
+	return r, nil
+}
+
+// parseNeighborhood parses a mask or a list of x,y offsets
+func parseNeighborhood(lines []string) (Neighborhood, error) {
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("Empty @NEIGHBORHOOD")
+	}
+	var n Neighborhood
+	if strings.Contains(lines[0], ",") {
+		for _, field := range strings.Fields(strings.Join(lines, " ")) {
+			xy := strings.Split(field, ",")
+			if len(xy) != 2 {
+				return nil, fmt.Errorf("Neighbor offsets must be x,y: %s", field)
+			}
+			x, err := strconv.Atoi(xy[0])
+			if err != nil {
+				return nil, fmt.Errorf("Error parsing neighbor offset: %s", err)
+			}
+			y, err := strconv.Atoi(xy[1])
+			if err != nil {
+				return nil, fmt.Errorf("Error parsing neighbor offset: %s", err)
+			}
+			n = append(n, [2]int{x, y})
+		}
+	} else {
+		if len(lines)%2 == 0 {
+			return nil, fmt.Errorf("Neighborhood mask must have an odd number of rows")
+		}
+		size := len(lines[0])
+		if size%2 == 0 {
+			return nil, fmt.Errorf("Neighborhood mask must have an odd number of columns")
+		}
+		for y, line := range lines {
+			if len(line) != size {
+				return nil, fmt.Errorf("Neighborhood mask rows must all be the same length")
+			}
+			for x, c := range line {
+				if c == '*' {
+					n = append(n, [2]int{x - size/2, y - len(lines)/2})
+				} else if c != '.' {
+					return nil, fmt.Errorf("Illegal characters in neighborhood mask: %s", line)
+				}
+			}
+		}
+	}
+
+	seen := make(map[[2]int]bool)
+	for _, xy := range n {
+		if xy[0] < -maxNeighborhoodRange || xy[0] > maxNeighborhoodRange || xy[1] < -maxNeighborhoodRange || xy[1] > maxNeighborhoodRange {
+			return nil, fmt.Errorf("Neighbor %d,%d is more than %d cells away", xy[0], xy[1], maxNeighborhoodRange)
+		}
+		if seen[xy] {
+			return nil, fmt.Errorf("Neighbor %d,%d is listed more than once", xy[0], xy[1])
+		}
+		seen[xy] = true
+	}
+	if len(n) == 0 {
+		return nil, fmt.Errorf("Neighborhood has no cells")
+	}
+	return n, nil
+}
+
+// parseCounts parses a list of neighbor counts separated by spaces or commas
+func parseCounts(lines []string, max int) (map[int]bool, error) {
+	counts := make(map[int]bool)
+	for _, field := range strings.Fields(strings.ReplaceAll(strings.Join(lines, " "), ",", " ")) {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing count: %s", err)
+		}
+		if n < 0 || n > max {
+			return nil, fmt.Errorf("Count %d must be from 0 to %d", n, max)
+		}
+		counts[n] = true
+	}
+	return counts, nil
+}
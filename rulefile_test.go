@@ -0,0 +1,146 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRuleFile(t *testing.T) {
+	var matrix = []struct {
+		rule         string
+		neighborhood Neighborhood
+		birth        map[int]bool
+		stayAlive    map[int]bool
+	}{
+		{
+			"@RULE Cross\n@NEIGHBORHOOD\n.*.\n*.*\n.*.\n@BIRTH 1 3\n@SURVIVAL\n# Comment\n1, 2\n",
+			Neighborhood{{0, -1}, {-1, 0}, {1, 0}, {0, 1}},
+			map[int]bool{1: true, 3: true},
+			map[int]bool{1: true, 2: true},
+		},
+		{
+			"@RULE Self\nIncludes the cell itself\n@NEIGHBORHOOD 0,0 1,0\n2,0\n@BIRTH\n@SURVIVAL 0",
+			Neighborhood{{0, 0}, {1, 0}, {2, 0}},
+			map[int]bool{},
+			map[int]bool{0: true},
+		},
+	}
+	for _, tt := range matrix {
+		r, err := ParseRuleFile(strings.Split(tt.rule, "\n"))
+		if err != nil {
+			t.Errorf("%q: %s", tt.rule, err)
+			continue
+		}
+		if !reflect.DeepEqual(r.Neighborhood, tt.neighborhood) {
+			t.Errorf("%q: expected neighborhood %v, got %v", tt.rule, tt.neighborhood, r.Neighborhood)
+		}
+		if !reflect.DeepEqual(r.Birth, tt.birth) || !reflect.DeepEqual(r.StayAlive, tt.stayAlive) {
+			t.Errorf("%q: expected %v/%v, got %v/%v", tt.rule, tt.birth, tt.stayAlive, r.Birth, r.StayAlive)
+		}
+	}
+
+	for _, rule := range []string{
+		"@NEIGHBORHOOD 1,0\n@BIRTH 1\n@SURVIVAL 1",
+		"@RULE Missing\n@BIRTH 1\n@SURVIVAL 1",
+		"@RULE Even\n@NEIGHBORHOOD\n**\n**\n@BIRTH 1\n@SURVIVAL 1",
+		"@RULE Far\n@NEIGHBORHOOD 11,0\n@BIRTH 1\n@SURVIVAL 1",
+		"@RULE Twice\n@NEIGHBORHOOD 1,0 1,0\n@BIRTH 1\n@SURVIVAL 1",
+		"@RULE Count\n@NEIGHBORHOOD 1,0\n@BIRTH 2\n@SURVIVAL 1",
+	} {
+		if _, err := ParseRuleFile(strings.Split(rule, "\n")); err == nil {
+			t.Errorf("%q: did not return an error", rule)
+		}
+	}
+}
+
+func TestLookupRule(t *testing.T) {
+	keepConfig(t)
+
+	dir, err := ioutil.TempDir("", "sdl2-life-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg.RuleDir = dir
+	rule := "@RULE Pair\n@NEIGHBORHOOD 1,0 -1,0\n@BIRTH 1\n@SURVIVAL 1 2\n"
+	path := filepath.Join(dir, "Pair.rule")
+	if err := ioutil.WriteFile(path, []byte(rule), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"Pair", path} {
+		r, err := LookupRule(name)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if r.Name != "Pair" || len(r.Neighborhood) != 2 {
+			t.Errorf("%s: wrong rule %#v", name, r)
+		}
+	}
+
+	r, err := LookupRule("B2/S013V")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Neighborhood, VonNeumannNeighborhood) || !r.Birth[2] || !r.StayAlive[3] {
+		t.Errorf("B2/S013V parsed as %#v", r)
+	}
+}
+
+func TestRuleFilePaths(t *testing.T) {
+	keepConfig(t)
+
+	dir := t.TempDir()
+	cfg.RuleDir = filepath.Join(dir, "rules")
+	if err := os.Mkdir(cfg.RuleDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	rule := "@RULE Pair\n@NEIGHBORHOOD 1,0 -1,0\n@BIRTH 1\n@SURVIVAL 1 2\n"
+	for _, path := range []string{filepath.Join(cfg.RuleDir, "Pair.rule"), filepath.Join(dir, "Outside.rule")} {
+		if err := ioutil.WriteFile(path, []byte(rule), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	outside := filepath.Join(dir, "Outside.rule")
+	if err := os.Mkdir(filepath.Join(cfg.RuleDir, "Dir.rule"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var matrix = []struct {
+		rule      string
+		allowPath bool
+		found     bool
+	}{
+		{"Pair", false, true},
+		{"Pair", true, true},
+		{outside, true, true},
+		{outside, false, false},
+		{"../Outside", false, false},
+		{"../Outside", true, true},
+		{"Outside.rule", false, false},
+		{"Dir", true, false},
+	}
+	for _, m := range matrix {
+		if path := ruleFilePath(m.rule, m.allowPath); (len(path) > 0) != m.found {
+			t.Errorf("%s allowPath=%v: expected found %v, got %q", m.rule, m.allowPath, m.found, path)
+		}
+	}
+
+	// The API and patterns can only use the paths of the rule already in use
+	g := newTestGame(8, 8)
+	cfg.Rule = "B3/S23"
+	if err := g.UseRule(outside); err == nil {
+		t.Errorf("expected an error using %s", outside)
+	}
+	if err := g.UseRule("Pair"); err != nil {
+		t.Errorf("unexpected error using Pair: %s", err)
+	}
+	cfg.Rule = outside
+	if err := g.UseRule(outside); err != nil || g.rule.Name != "Pair" {
+		t.Errorf("unexpected error using -rule %s: %v", outside, err)
+	}
+}
@@ -0,0 +1,414 @@
+// sdl2-life
+// by Brian C. Lane <bcl@brianlane.com>
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxTableStates is the largest number of states in a rule table
+	maxTableStates = 256
+	// maxTableCache is the number of neighborhoods to remember the next state of
+	maxTableCache = 1 << 20
+)
+
+var (
+	// tableMoore is the Moore neighborhood in the order used by rule tables, N, NE, E, SE, S, SW, W, NW
+	tableMoore = Neighborhood{{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}}
+	// tableVonNeumann is the von Neumann neighborhood in the order used by rule tables, N, E, S, W
+	tableVonNeumann = Neighborhood{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+	// tableHex is the hexagonal neighborhood in the order used by rule tables, N, E, SE, S, W, NW
+	tableHex = Neighborhood{{0, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 0}, {-1, -1}}
+)
+
+// tableTerm is one cell of a transition, a state or a variable
+type tableTerm struct {
+	states []bool // States that match, indexed by state
+	bind   int    // Index of the variable that is bound to the state, -1 for a state
+}
+
+// transition is one line of a rule table
+type transition struct {
+	inputs  []tableTerm // The cell, then its neighbors
+	output  int         // Next state of the cell
+	outVar  int         // Index of the variable to use as the next state, -1 uses output
+	permute bool        // The neighbors can match in any order
+}
+
+// RuleTable is a multi-state rule from the @TABLE section of a rule file
+// The transitions are checked in order, and the first one that matches gives the next
+// state of the cell. If none of them match the cell stays the same.
+type RuleTable struct {
+	States      int                 // Number of states, 0 is the background
+	Neighbors   Neighborhood        // Neighbors in the order used by the transitions
+	Colors      map[uint8]RGBAColor // Colors of the states from @COLORS
+	transitions []transition
+	cache       map[[9]uint8]uint8 // Next state for neighborhoods that have been seen
+}
+
+// ParseRuleTable parses the @TABLE section of a rule file, with its @COLORS
+//
+//	n_states:4
+//	neighborhood:Moore
+//	symmetries:rotate8
+//	var a={0,1,2,3}
+//	0,1,a,0,0,0,0,0,0,2
+//
+// The neighborhood is Moore, vonNeumann, or hexagonal. Each transition lists the state of
+// the cell, its neighbors clockwise starting at N, and the cell's next state. Variables
+// match any of their states, and each use of the same variable in a transition matches the
+// same state. With 10 states or less the commas can be left out. The symmetries are none,
+// rotateN, rotateNreflect, reflect_horizontal, or permute.
+func ParseRuleTable(lines, colors []string) (*RuleTable, error) {
+	t := &RuleTable{Colors: make(map[uint8]RGBAColor), cache: make(map[[9]uint8]uint8)}
+	vars := make(map[string][]bool)
+	symmetries := "none"
+	for _, line := range lines {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		if fields := strings.SplitN(line, ":", 2); len(fields) == 2 {
+			value := strings.TrimSpace(fields[1])
+			switch strings.TrimSpace(fields[0]) {
+			case "n_states":
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 2 || n > maxTableStates {
+					return nil, fmt.Errorf("n_states must be from 2 to %d: %s", maxTableStates, value)
+				}
+				t.States = n
+			case "neighborhood":
+				switch strings.ToLower(value) {
+				case "moore":
+					t.Neighbors = tableMoore
+				case "vonneumann":
+					t.Neighbors = tableVonNeumann
+				case "hexagonal":
+					t.Neighbors = tableHex
+				default:
+					return nil, fmt.Errorf("Unsupported neighborhood: %s", value)
+				}
+			case "symmetries":
+				symmetries = value
+			default:
+				return nil, fmt.Errorf("Unknown setting: %s", line)
+			}
+			continue
+		}
+		if t.States == 0 || t.Neighbors == nil {
+			return nil, fmt.Errorf("n_states and neighborhood must come before the transitions")
+		}
+
+		if strings.HasPrefix(line, "var ") {
+			name, states, err := t.parseVar(line[4:], vars)
+			if err != nil {
+				return nil, err
+			}
+			vars[name] = states
+			continue
+		}
+
+		tr, err := t.parseTransition(line, vars)
+		if err != nil {
+			return nil, err
+		}
+		variants, err := t.symmetric(tr, symmetries)
+		if err != nil {
+			return nil, err
+		}
+		t.transitions = append(t.transitions, variants...)
+	}
+	if t.States == 0 || t.Neighbors == nil {
+		return nil, fmt.Errorf("Missing n_states or neighborhood")
+	}
+	if err := t.parseColors(colors); err != nil {
+		return nil, fmt.Errorf("@COLORS: %s", err)
+	}
+	return t, nil
+}
+
+// parseState parses a state, it must be less than the number of states
+func (t *RuleTable) parseState(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n >= t.States {
+		return 0, fmt.Errorf("State must be from 0 to %d: %s", t.States-1, s)
+	}
+	return n, nil
+}
+
+// parseVar parses a variable like a={0,1,2}, the list can include other variables
+func (t *RuleTable) parseVar(line string, vars map[string][]bool) (string, []bool, error) {
+	fields := strings.SplitN(line, "=", 2)
+	if len(fields) != 2 {
+		return "", nil, fmt.Errorf("Variables must be var name={states}: %s", line)
+	}
+	name := strings.TrimSpace(fields[0])
+	if len(name) == 0 || strings.ContainsAny(name, ", {}") {
+		return "", nil, fmt.Errorf("Bad variable name: %q", name)
+	}
+	if _, ok := vars[name]; ok {
+		return "", nil, fmt.Errorf("Variable %s is already defined", name)
+	}
+	value := strings.TrimSpace(fields[1])
+	if !strings.HasPrefix(value, "{") || !strings.HasSuffix(value, "}") {
+		return "", nil, fmt.Errorf("Variables must be var name={states}: %s", line)
+	}
+	states := make([]bool, t.States)
+	for _, s := range strings.Split(value[1:len(value)-1], ",") {
+		s = strings.TrimSpace(s)
+		if v, ok := vars[s]; ok {
+			for i := range v {
+				states[i] = states[i] || v[i]
+			}
+			continue
+		}
+		n, err := t.parseState(s)
+		if err != nil {
+			return "", nil, fmt.Errorf("Variable %s: %s", name, err)
+		}
+		states[n] = true
+	}
+	return name, states, nil
+}
+
+// parseTransition parses the states or variables of the cell, its neighbors, and the next state
+func (t *RuleTable) parseTransition(line string, vars map[string][]bool) (transition, error) {
+	var tokens []string
+	if strings.Contains(line, ",") {
+		for _, s := range strings.Split(line, ",") {
+			tokens = append(tokens, strings.TrimSpace(s))
+		}
+	} else if t.States <= 10 {
+		for _, c := range line {
+			tokens = append(tokens, string(c))
+		}
+	}
+	if len(tokens) != len(t.Neighbors)+2 {
+		return transition{}, fmt.Errorf("Transition must have %d states: %s", len(t.Neighbors)+2, line)
+	}
+
+	tr := transition{outVar: -1}
+	bound := make(map[string]int)
+	for _, s := range tokens[:len(tokens)-1] {
+		term := tableTerm{bind: -1}
+		if v, ok := vars[s]; ok {
+			term.states = v
+			if _, ok := bound[s]; !ok {
+				bound[s] = len(bound)
+			}
+			term.bind = bound[s]
+		} else {
+			n, err := t.parseState(s)
+			if err != nil {
+				return tr, err
+			}
+			term.states = make([]bool, t.States)
+			term.states[n] = true
+		}
+		tr.inputs = append(tr.inputs, term)
+	}
+
+	out := tokens[len(tokens)-1]
+	if i, ok := bound[out]; ok {
+		tr.outVar = i
+	} else if _, ok := vars[out]; ok {
+		return tr, fmt.Errorf("Variable %s is not in the transition: %s", out, line)
+	} else {
+		n, err := t.parseState(out)
+		if err != nil {
+			return tr, err
+		}
+		tr.output = n
+	}
+	return tr, nil
+}
+
+// symmetric returns the transition with its neighbors rotated and reflected
+func (t *RuleTable) symmetric(tr transition, symmetries string) ([]transition, error) {
+	k := len(t.Neighbors)
+	var reflect bool
+	step := k
+	switch {
+	case symmetries == "none":
+	case symmetries == "reflect_horizontal":
+		reflect = true
+	case symmetries == "permute":
+		// Checked by matching the neighbors in any order, instead of listing every order
+		tr.permute = true
+	case strings.HasPrefix(symmetries, "rotate"):
+		s := strings.TrimPrefix(symmetries, "rotate")
+		if strings.HasSuffix(s, "reflect") {
+			reflect = true
+			s = strings.TrimSuffix(s, "reflect")
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || k%n != 0 {
+			return nil, fmt.Errorf("Unsupported symmetries: %s", symmetries)
+		}
+		step = k / n
+	default:
+		return nil, fmt.Errorf("Unsupported symmetries: %s", symmetries)
+	}
+
+	variants := []transition{tr}
+	seen := map[string]bool{tr.key(): true}
+	for r := 0; r < k; r += step {
+		for _, flip := range []bool{false, true} {
+			if flip && !reflect {
+				continue
+			}
+			v := tr
+			v.inputs = make([]tableTerm, len(tr.inputs))
+			v.inputs[0] = tr.inputs[0]
+			for i := 0; i < k; i++ {
+				j := (i + r) % k
+				if flip {
+					j = (k - j) % k
+				}
+				v.inputs[i+1] = tr.inputs[j+1]
+			}
+			if key := v.key(); !seen[key] {
+				seen[key] = true
+				variants = append(variants, v)
+			}
+		}
+	}
+	return variants, nil
+}
+
+// key returns a string that is the same for transitions with the same inputs
+func (tr transition) key() string {
+	var b strings.Builder
+	for _, term := range tr.inputs {
+		fmt.Fprintf(&b, "%d%v,", term.bind, term.states)
+	}
+	return b.String()
+}
+
+// parseColors parses the @COLORS section
+// Each line is a state and its red, green, and blue values from 0 to 255. A line with 2
+// colors, r g b r g b, is a gradient from state 1 to the last state.
+func (t *RuleTable) parseColors(lines []string) error {
+	for _, line := range lines {
+		var values []uint8
+		for _, s := range strings.Fields(line) {
+			n, err := strconv.ParseUint(s, 10, 8)
+			if err != nil {
+				return fmt.Errorf("Values must be from 0 to 255: %s", line)
+			}
+			values = append(values, uint8(n))
+		}
+		switch len(values) {
+		case 4:
+			if int(values[0]) >= t.States {
+				return fmt.Errorf("State must be from 0 to %d: %s", t.States-1, line)
+			}
+			t.Colors[values[0]] = RGBAColor{values[1], values[2], values[3], 255}
+		case 6:
+			for s := 1; s < t.States; s++ {
+				f := 0.0
+				if t.States > 2 {
+					f = float64(s-1) / float64(t.States-2)
+				}
+				mix := func(from, to uint8) uint8 {
+					return uint8(float64(from) + f*(float64(to)-float64(from)))
+				}
+				t.Colors[uint8(s)] = RGBAColor{mix(values[0], values[3]), mix(values[1], values[4]), mix(values[2], values[5]), 255}
+			}
+		default:
+			return fmt.Errorf("Colors must be state r g b, or r g b r g b: %s", line)
+		}
+	}
+	return nil
+}
+
+// Next returns the next state of a cell, given the states of the cell and its neighbors
+func (t *RuleTable) Next(cells [9]uint8) uint8 {
+	if next, ok := t.cache[cells]; ok {
+		return next
+	}
+	next := cells[0]
+	n := len(t.Neighbors) + 1
+	for _, tr := range t.transitions {
+		var bound [9]int
+		if !tr.inputs[0].match(cells[0], &bound) {
+			continue
+		}
+		var ok bool
+		if tr.permute {
+			ok = matchAny(tr.inputs[1:], cells[1:n], 0, &bound)
+		} else {
+			ok = true
+			for i := 1; i < n && ok; i++ {
+				ok = tr.inputs[i].match(cells[i], &bound)
+			}
+		}
+		if ok {
+			next = uint8(tr.output)
+			if tr.outVar >= 0 {
+				next = uint8(bound[tr.outVar] - 1)
+			}
+			break
+		}
+	}
+	if len(t.cache) >= maxTableCache {
+		t.cache = make(map[[9]uint8]uint8)
+	}
+	t.cache[cells] = next
+	return next
+}
+
+// match returns true if the state matches the term, binding its variable to the state
+// bound holds the state+1 of each variable, 0 if it has not been bound yet.
+func (term tableTerm) match(state uint8, bound *[9]int) bool {
+	if int(state) >= len(term.states) || !term.states[state] {
+		return false
+	}
+	if term.bind < 0 {
+		return true
+	}
+	if bound[term.bind] != 0 {
+		return bound[term.bind] == int(state)+1
+	}
+	bound[term.bind] = int(state) + 1
+	return true
+}
+
+// matchAny returns true if the states match the terms in any order
+// used marks the terms that have already been matched to a state.
+func matchAny(terms []tableTerm, states []uint8, used int, bound *[9]int) bool {
+	if len(states) == 0 {
+		return true
+	}
+	for i, term := range terms {
+		if used&(1<<uint(i)) != 0 {
+			continue
+		}
+		saved := *bound
+		if term.match(states[0], bound) && matchAny(terms, states[1:], used|1<<uint(i), bound) {
+			return true
+		}
+		*bound = saved
+	}
+	return false
+}
+
+// tableNeighbors returns the states of a cell and its neighbors, for RuleTable.Next
+func (g *LifeGame) tableNeighbors(c *Cell) [9]uint8 {
+	var cells [9]uint8
+	cells[0] = c.state
+	for i, offset := range g.rule.Table.Neighbors {
+		// If we're at an edge, check the other side of the board.
+		x := ((c.x+offset[0])%g.columns + g.columns) % g.columns
+		y := ((c.y+offset[1])%g.rows + g.rows) % g.rows
+		cells[i+1] = g.cells[y][x].state
+	}
+	return cells
+}
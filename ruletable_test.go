@@ -0,0 +1,167 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// tableGame returns a game using the rule, with the cells set from rows of states
+func tableGame(r Rule, rows []string) *LifeGame {
+	g := newTestGame(len(rows[0]), len(rows))
+	g.SetRule(r)
+	for y, row := range rows {
+		for x, c := range row {
+			g.SetCell(x, y, uint8(c-'0'))
+		}
+	}
+	return g
+}
+
+// tableStates returns the states of the cells as rows of digits
+func tableStates(g *LifeGame) []string {
+	var rows []string
+	for y := range g.cells {
+		var row []byte
+		for _, c := range g.cells[y] {
+			row = append(row, '0'+c.state)
+		}
+		rows = append(rows, string(row))
+	}
+	return rows
+}
+
+func TestRuleTable(t *testing.T) {
+	var matrix = []struct {
+		name  string
+		rule  string
+		start []string
+		next  []string
+	}{
+		{
+			"no symmetry",
+			"@RULE t\n@TABLE\nn_states:3\nneighborhood:vonNeumann\nsymmetries:none\n0,1,0,0,0,2",
+			[]string{"000", "010", "000"},
+			[]string{"000", "010", "020"},
+		},
+		{
+			"rotate4",
+			"@RULE t\n@TABLE\nn_states:3\nneighborhood:vonNeumann\nsymmetries:rotate4\n0,1,0,0,0,2",
+			[]string{"000", "010", "000"},
+			[]string{"020", "212", "020"},
+		},
+		{
+			"bound variables match the same state",
+			"@RULE t\n@TABLE\nn_states:3\nneighborhood:vonNeumann\nsymmetries:none\nvar a={1,2}\n0,a,0,a,0,a",
+			[]string{"01010", "00000", "02010"},
+			[]string{"01010", "00010", "02010"},
+		},
+		{
+			"no commas, and no match stays the same",
+			"@RULE t\n@TABLE\nn_states:3\nneighborhood:Moore\nsymmetries:permute\n1110000002",
+			[]string{"000", "110", "000"},
+			[]string{"000", "110", "000"},
+		},
+		{
+			"permute",
+			"@RULE t\n@TABLE\nn_states:3\nneighborhood:Moore\nsymmetries:permute\n1110000002",
+			[]string{"10000", "01000", "00100", "00000", "00000"},
+			[]string{"10000", "02000", "00100", "00000", "00000"},
+		},
+	}
+
+	for _, tt := range matrix {
+		r, err := ParseRuleFile(strings.Split(tt.rule, "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := tableGame(r, tt.start)
+		g.NextFrame()
+		if next := tableStates(g); strings.Join(next, "/") != strings.Join(tt.next, "/") {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.next, next)
+		}
+	}
+}
+
+func TestRuleTableErrors(t *testing.T) {
+	for _, table := range []string{
+		"n_states:1\nneighborhood:Moore",
+		"n_states:4\nneighborhood:oneDimensional",
+		"n_states:4\nneighborhood:Moore\nsymmetries:rotate3\n0,1,0,0,0,0,0,0,0,1",
+		"n_states:4\nneighborhood:Moore\nsymmetries:mirror\n0,1,0,0,0,0,0,0,0,1",
+		"0,1,0,0,0,1",
+		"n_states:2\nneighborhood:vonNeumann\n0,1,0,0,1",
+		"n_states:2\nneighborhood:vonNeumann\n0,2,0,0,0,1",
+		"n_states:2\nneighborhood:vonNeumann\nvar a={0,2}",
+		"n_states:2\nneighborhood:vonNeumann\nvar a={0,1}\nvar a={0}",
+		"n_states:2\nneighborhood:vonNeumann\nvar a={0,1}\nvar b={1}\n0,a,0,0,0,b",
+		"n_states:2\nneighborhood:vonNeumann\nsize:3",
+	} {
+		if _, err := ParseRuleTable(strings.Split(table, "\n"), nil); err == nil {
+			t.Errorf("%q: did not return an error", table)
+		}
+	}
+
+	for _, colors := range []string{"4 0 0 0", "1 0 0 256", "1 2 3"} {
+		if _, err := ParseRuleTable([]string{"n_states:4", "neighborhood:Moore"}, []string{colors}); err == nil {
+			t.Errorf("%q: did not return an error", colors)
+		}
+	}
+}
+
+func TestWireWorld(t *testing.T) {
+	r, err := LookupRule("WireWorld")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Table == nil || r.Table.States != 4 || r.Table.Colors[3] != (RGBAColor{255, 128, 0, 255}) {
+		t.Fatalf("WireWorld parsed as %#v", r.Table)
+	}
+
+	// An electron going around a loop of wire
+	rows := []string{
+		"00000000",
+		"00333300",
+		"03000030",
+		"00321300",
+		"00000000",
+	}
+	g := tableGame(r, rows)
+	g.NextFrame()
+	expected := []string{"00000000", "00333300", "03000030", "00332100", "00000000"}
+	if states := tableStates(g); strings.Join(states, "/") != strings.Join(expected, "/") {
+		t.Errorf("expected %v, got %v", expected, states)
+	}
+	for i := 1; i < 10; i++ {
+		g.NextFrame()
+	}
+	if states := tableStates(g); strings.Join(states, "/") != strings.Join(rows, "/") {
+		t.Errorf("expected %v after 10 generations, got %v", rows, states)
+	}
+	if g.liveCells != 10 {
+		t.Errorf("expected 10 live cells, got %d", g.liveCells)
+	}
+}
+
+func TestMultiStateRLE(t *testing.T) {
+	g := newTestGame(8, 8)
+	if _, err := g.LoadPattern([]string{"x = 5, y = 1", "A.2pAyO!"}, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	var states []uint8
+	for x := 0; x < 5; x++ {
+		c := g.cells[4][(x+2)%8]
+		states = append(states, c.state)
+		if c.alive != (c.state != 0) {
+			t.Errorf("cell %d state %d has alive %v", x, c.state, c.alive)
+		}
+	}
+	if string(states) != string([]uint8{1, 0, 25, 25, 255}) {
+		t.Errorf("expected 1 0 25 25 255, got %v", states)
+	}
+
+	for _, rle := range []string{"2p$!", "pz!", "yP!"} {
+		if _, err := g.LoadPattern([]string{"x = 5, y = 1", rle}, 0, 0); err == nil {
+			t.Errorf("%s: did not return an error", rle)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+// sdl2-life
+// by Brian C. Lane <bcl@brianlane.com>
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+)
+
+const (
+	// snapshotVersion is incremented when the snapshot format changes
+	snapshotVersion = 1
+	// maxSnapshotCells is the largest world that can be restored from a snapshot
+	maxSnapshotCells = 1 << 24
+	// maxSnapshotBytes is the largest snapshot accepted by the server
+	maxSnapshotBytes = 64 << 20
+)
+
+// SnapshotCell holds the state of one cell
+// Only cells that are alive, or have a heat map or trail history, are saved
+type SnapshotCell struct {
+	X     int     `json:"x"`
+	Y     int     `json:"y"`
+	Alive bool    `json:"alive,omitempty"`
+	State uint8   `json:"state,omitempty"` // State for multi-state rules, when it is not 1
+	Age   int     `json:"age,omitempty"`
+	Heat  float64 `json:"heat,omitempty"`
+	Death int     `json:"death,omitempty"`
+}
+
+// SnapshotSettings holds the cfg settings that affect how the world looks and runs
+type SnapshotSettings struct {
+	CellSize    int     `json:"cell_size"`
+	Border      bool    `json:"border"`
+	Fps         int     `json:"fps"`
+	Color       bool    `json:"color"`
+	Colors      string  `json:"colors"`
+	Gradient    int     `json:"gradient"`
+	MaxAge      int     `json:"max_age"`
+	HeatMap     bool    `json:"heatmap"`
+	HeatDecay   float64 `json:"heat_decay"`
+	Trails      bool    `json:"trails"`
+	TrailLength int     `json:"trail_length"`
+	TrailColor  string  `json:"trail_color"`
+	Noise       float64 `json:"noise,omitempty"`
+}
+
+// Snapshot holds everything needed to resume a game exactly where it was saved
+type Snapshot struct {
+	Version    int              `json:"version"`
+	Columns    int              `json:"columns"`
+	Rows       int              `json:"rows"`
+	Topology   string           `json:"topology"`
+	Generation int64            `json:"generation"`
+	Phase      int              `json:"phase,omitempty"`
+	NoiseSeed  int64            `json:"noise_seed,omitempty"`
+	Age        int64            `json:"age"`
+	LiveCells  int              `json:"live_cells"`
+	Rule       string           `json:"rule"`
+	Pattern    PatternInfo      `json:"pattern"`
+	Settings   SnapshotSettings `json:"settings"`
+	Cells      []SnapshotCell   `json:"cells"`
+}
+
+// SnapshotRequest is used to pass snapshot requests from the API to the game
+// If snapshot is nil the current state is returned, otherwise it is restored.
+type SnapshotRequest struct {
+	snapshot *Snapshot
+	reply    chan<- SnapshotReply
+}
+
+// SnapshotReply holds the result of a SnapshotRequest
+type SnapshotReply struct {
+	snapshot *Snapshot
+	err      error
+}
+
+// Snapshot returns the current state of the game
+func (g *LifeGame) Snapshot() *Snapshot {
+	s := &Snapshot{
+		Version:    snapshotVersion,
+		Columns:    g.columns,
+		Rows:       g.rows,
+		Topology:   "torus",
+		Generation: g.generation,
+		Phase:      g.phase,
+		NoiseSeed:  g.noiseSeed,
+		Age:        g.age,
+		LiveCells:  g.liveCells,
+		Rule:       g.RuleName(),
+		Pattern:    g.info,
+		Settings: SnapshotSettings{
+			CellSize:    cfg.CellSize,
+			Border:      cfg.Border,
+			Fps:         cfg.Fps,
+			Color:       cfg.Color,
+			Colors:      cfg.Colors,
+			Gradient:    cfg.Gradient,
+			MaxAge:      cfg.MaxAge,
+			HeatMap:     cfg.HeatMap,
+			HeatDecay:   cfg.HeatDecay,
+			Trails:      cfg.Trails,
+			TrailLength: cfg.TrailLength,
+			TrailColor:  cfg.TrailColor,
+			Noise:       cfg.Noise,
+		},
+	}
+
+	for y := range g.cells {
+		for _, c := range g.cells[y] {
+			if !c.alive && c.heat <= heatThreshold && c.death == 0 {
+				continue
+			}
+			sc := SnapshotCell{X: c.x, Y: c.y, Alive: c.alive, Age: c.age, Heat: c.heat, Death: c.death}
+			if c.state > 1 {
+				sc.State = c.state
+			}
+			s.Cells = append(s.Cells, sc)
+		}
+	}
+	return s
+}
+
+// Restore replaces the current game with the snapshot
+// The settings are checked the same way as the cmdline flags, if there is a problem
+// the game is left unchanged. The world is restored at the snapshot's size, with smaller
+// cells if needed to fit it in the window. If it doesn't fit, even with 1 pixel cells, an
+// error is returned instead of dropping the cells at the edges.
+func (g *LifeGame) Restore(s *Snapshot) error {
+	if s.Version != snapshotVersion {
+		return fmt.Errorf("Unsupported snapshot version %d", s.Version)
+	}
+	if s.Topology != "torus" {
+		return fmt.Errorf("Unsupported topology %q", s.Topology)
+	}
+	if s.Columns < 1 || s.Rows < 1 {
+		return fmt.Errorf("Snapshot world size must be 1 or more")
+	}
+	if s.Columns > maxSnapshotCells/s.Rows {
+		return fmt.Errorf("Snapshot world is larger than %d cells", maxSnapshotCells)
+	}
+	for _, c := range s.Cells {
+		if c.X < 0 || c.X >= s.Columns || c.Y < 0 || c.Y >= s.Rows {
+			return fmt.Errorf("Cell %d, %d is outside the world", c.X, c.Y)
+		}
+	}
+	// The rule file path is only used if it is already in use, eg. from -rule
+	rule, err := lookupRule(s.Rule, s.Rule == cfg.Rule)
+	if err != nil {
+		return fmt.Errorf("Problem with snapshot rule: %s", err)
+	}
+	if s.Phase < 0 || s.Phase >= len(rule.Steps()) {
+		return fmt.Errorf("Snapshot phase %d is not valid for rule %s", s.Phase, s.Rule)
+	}
+
+	old := cfg
+	cfg.CellSize = s.Settings.CellSize
+	cfg.Border = s.Settings.Border
+	cfg.Fps = s.Settings.Fps
+	cfg.Color = s.Settings.Color
+	cfg.Colors = s.Settings.Colors
+	cfg.Gradient = s.Settings.Gradient
+	cfg.MaxAge = s.Settings.MaxAge
+	cfg.HeatMap = s.Settings.HeatMap
+	cfg.HeatDecay = s.Settings.HeatDecay
+	cfg.Trails = s.Settings.Trails
+	cfg.TrailLength = s.Settings.TrailLength
+	cfg.TrailColor = s.Settings.TrailColor
+	cfg.Noise = s.Settings.Noise
+	if err := validateArgs(); err != nil {
+		cfg = old
+		return fmt.Errorf("Problem with snapshot settings: %s", err)
+	}
+	if err := g.fitSnapshot(s.Columns, s.Rows); err != nil {
+		cfg = old
+		return err
+	}
+
+	// The snapshot's rule is kept with its pattern, -rule is left alone
+	g.SetPatternInfo(s.Pattern)
+	g.SetRule(rule)
+	g.info.Rule = s.Rule
+	if len(rule.Rulestring) > 0 {
+		g.info.Rule = rule.Rulestring
+	}
+	g.phase = s.Phase
+	g.noiseSeed = s.NoiseSeed
+	g.generation = s.Generation
+	g.age = s.Age
+	g.InitializeColors()
+
+	// Fill the world at the snapshot's size
+	g.columns, g.rows = s.Columns, s.Rows
+	g.ClearCells()
+	g.liveCells = 0
+	for _, sc := range s.Cells {
+		c := g.cells[sc.Y][sc.X]
+		c.state = sc.State
+		if c.state == 0 && sc.Alive {
+			c.state = 1
+		}
+		c.stateNext = c.state
+		c.alive, c.aliveNext = c.state != 0, c.state != 0
+		c.age, c.heat, c.death = sc.Age, sc.Heat, sc.Death
+		if c.alive {
+			g.liveCells++
+		}
+	}
+
+	g.status = fmt.Sprintf("age: %5d alive: %5d", g.age, g.liveCells)
+	if g.canvas != nil {
+		g.Draw(g.status)
+	}
+	return nil
+}
+
+// fitSnapshot sets the cell size so that a world of columns x rows fits in the window
+// Without a window the size is set to fit the world, there is nothing for it to fit into.
+func (g *LifeGame) fitSnapshot(columns, rows int) error {
+	if g.canvas == nil {
+		cfg.Width, cfg.Height = columns*cfg.CellSize, rows*cfg.CellSize
+		return nil
+	}
+	oldColumns, oldRows := g.columns, g.rows
+	g.columns, g.rows = columns, rows
+	size := g.FitCellSize()
+	g.columns, g.rows = oldColumns, oldRows
+	if size < 1 {
+		return fmt.Errorf("Snapshot world of %d x %d cells does not fit in the window", columns, rows)
+	}
+	if size < cfg.CellSize {
+		cfg.CellSize = size
+	}
+	return nil
+}
+
+// SaveSnapshot writes the current state of the game to a file
+func (g *LifeGame) SaveSnapshot(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = writeSnapshot(f, g.Snapshot()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadSnapshot restores the game from a file
+func (g *LifeGame) LoadSnapshot(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s, err := readSnapshot(f)
+	if err != nil {
+		return err
+	}
+	return g.Restore(s)
+}
+
+// writeSnapshot writes the snapshot as JSON
+func writeSnapshot(w io.Writer, s *Snapshot) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// readSnapshot reads a JSON snapshot
+func readSnapshot(r io.Reader) (*Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("Problem reading snapshot: %s", err)
+	}
+	return &s, nil
+}
+
+// HandleSnapshotRequest saves or restores a snapshot for the API
+func (g *LifeGame) HandleSnapshotRequest(req SnapshotRequest) {
+	if req.snapshot == nil {
+		req.reply <- SnapshotReply{snapshot: g.Snapshot()}
+		return
+	}
+	req.reply <- SnapshotReply{err: g.Restore(req.snapshot)}
+}
+
+// snapshotHandler returns the API handler for /snapshot
+// GET returns the current snapshot, POST restores the snapshot in the body
+func snapshotHandler(sChan chan<- SnapshotRequest) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reply := make(chan SnapshotReply)
+		switch r.Method {
+		case "GET":
+			sChan <- SnapshotRequest{reply: reply}
+			result := <-reply
+			w.Header().Set("Content-Type", "application/json")
+			if err := writeSnapshot(w, result.snapshot); err != nil {
+				log.Printf("Failed to send snapshot: %s\n", err)
+			}
+		case "POST":
+			s, err := readSnapshot(http.MaxBytesReader(w, r.Body, maxSnapshotBytes))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			sChan <- SnapshotRequest{snapshot: s, reply: reply}
+			if result := <-reply; result.err != nil {
+				http.Error(w, result.err.Error(), http.StatusBadRequest)
+			}
+		default:
+			http.Error(w, "", http.StatusMethodNotAllowed)
+		}
+	}
+}
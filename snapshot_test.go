@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	g := newTestGame(6, 5)
+	g.SetCellState(1, 2, true)
+	g.SetCellState(2, 2, true)
+	g.cells[2][1].age = 7
+	g.cells[3][3].heat = 2.5
+	g.cells[0][0].death = 3
+	g.liveCells = 2
+	g.generation = 42
+	g.age = 40
+	g.info = PatternInfo{Name: "Blinker", Author: "John Conway"}
+
+	var buf bytes.Buffer
+	if err := writeSnapshot(&buf, g.Snapshot()); err != nil {
+		t.Fatal(err)
+	}
+	s, err := readSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Cells) != 4 {
+		t.Fatalf("expected 4 cells in the snapshot, got %d", len(s.Cells))
+	}
+
+	// Restore into a bigger world, it keeps the snapshot's size
+	saved := keepConfig(t)
+	cfg.Width, cfg.Height = 8*cfg.CellSize, 7*cfg.CellSize
+	r := newTestGame(8, 7)
+	if err := r.Restore(s); err != nil {
+		t.Fatalf("Restore failed: %s", err)
+	}
+	if r.columns != 6 || r.rows != 5 || len(r.cells) != 5 || len(r.cells[0]) != 6 {
+		t.Fatalf("wrong world size: %d x %d", r.columns, r.rows)
+	}
+	if r.generation != 42 || r.age != 40 || r.liveCells != 2 {
+		t.Errorf("wrong counts: generation=%d age=%d liveCells=%d", r.generation, r.age, r.liveCells)
+	}
+	if !r.cells[2][1].alive || r.cells[2][1].age != 7 || !r.cells[2][2].alive {
+		t.Errorf("live cells were not restored")
+	}
+	if r.cells[3][3].heat != 2.5 || r.cells[0][0].death != 3 {
+		t.Errorf("cell history was not restored")
+	}
+	if r.info.Summary() != "Blinker by John Conway" {
+		t.Errorf("pattern info was not restored: %#v", r.info)
+	}
+
+	// A window that is too small uses smaller cells, instead of dropping the edges
+	cfg = saved
+	cfg.Width, cfg.Height = 64, 48
+	s.Settings.CellSize = 8
+	r = newTestGame(4, 4)
+	r.canvas = NewImageCanvas(cfg.Width, cfg.Height)
+	if err := r.Restore(s); err != nil {
+		t.Fatalf("Restore failed: %s", err)
+	}
+	if cfg.CellSize != 7 || r.columns != 6 || r.rows != 5 || r.liveCells != 2 {
+		t.Errorf("expected a 6 x 5 world with 7 pixel cells, got %d x %d with %d", r.columns, r.rows, cfg.CellSize)
+	}
+
+	// If it can't fit at all it is an error
+	cfg.Width, cfg.Height = 4, 30
+	if err := r.Restore(s); err == nil {
+		t.Errorf("expected an error restoring into a window that is too small")
+	}
+
+	// Bad settings are rejected and leave the game alone
+	s.Settings.Fps = 0
+	if err := r.Restore(s); err == nil {
+		t.Errorf("bad fps did not return an error")
+	}
+	if cfg.Fps == 0 {
+		t.Errorf("cfg was changed by a bad snapshot")
+	}
+}
+
+func TestSnapshotRule(t *testing.T) {
+	keepConfig(t)
+	cfg.Rule, cfg.Width, cfg.Height = "B3/S23", 6*cfg.CellSize, 5*cfg.CellSize
+
+	g := newTestGame(6, 5)
+	if err := g.UseRule("HighLife"); err != nil {
+		t.Fatal(err)
+	}
+	s := g.Snapshot()
+	if s.Rule != "B36/S23" {
+		t.Errorf("expected the rule in use, got %s", s.Rule)
+	}
+
+	// The snapshot's rule is used until the next pattern without a rule, which uses -rule
+	r := newTestGame(6, 5)
+	if err := r.Restore(s); err != nil {
+		t.Fatal(err)
+	}
+	if r.RuleName() != "B36/S23" || !r.rule.Birth[6] || cfg.Rule != "B3/S23" {
+		t.Errorf("expected B36/S23 with -rule B3/S23, got %s -rule %s", r.RuleName(), cfg.Rule)
+	}
+	if err := r.ApplyPatternRule(PatternInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if r.RuleName() != "B3/S23" || r.rule.Birth[6] {
+		t.Errorf("expected -rule B3/S23, got %s", r.RuleName())
+	}
+}
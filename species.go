@@ -0,0 +1,77 @@
+// sdl2-life
+// by Brian C. Lane <bcl@brianlane.com>
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// speciesRules are the catalogue rules where each live cell is one of several species
+// The state of a live cell is its species, from 1 to the number of species.
+var speciesRules = map[string]int{
+	"Immigration": 2,
+	"QuadLife":    4,
+}
+
+// speciesColors are the colors of the species, in order
+var speciesColors = []RGBAColor{
+	{255, 255, 0, 255},
+	{255, 64, 64, 255},
+	{64, 255, 64, 255},
+	{64, 128, 255, 255},
+}
+
+// species returns the species of a live state
+// States past the rule's species, eg. from a multi-state pattern, wrap around to the first one.
+func (g *LifeGame) species(state uint8) uint8 {
+	return uint8((int(state)-1)%g.rule.Species + 1)
+}
+
+// majoritySpecies returns the species of a new cell, the one most of its live neighbors are
+// If there is a tie it is the first species none of the neighbors are, so that 3 parents of
+// different species in QuadLife give birth to the 4th, otherwise the first of the tied species.
+func (g *LifeGame) majoritySpecies(c *Cell) uint8 {
+	counts := make([]int, g.rule.Species+1)
+	for _, offset := range g.rule.Neighborhood {
+		// If we're at an edge, check the other side of the board.
+		x := ((c.x+offset[0])%g.columns + g.columns) % g.columns
+		y := ((c.y+offset[1])%g.rows + g.rows) % g.rows
+
+		if n := g.cells[y][x]; n.alive {
+			counts[g.species(n.state)]++
+		}
+	}
+
+	var best uint8 = 1
+	var tied bool
+	for s := 2; s < len(counts); s++ {
+		if counts[s] > counts[best] {
+			best, tied = uint8(s), false
+		} else if counts[s] == counts[best] {
+			tied = true
+		}
+	}
+	if tied {
+		for s := 1; s < len(counts); s++ {
+			if counts[s] == 0 {
+				return uint8(s)
+			}
+		}
+	}
+	return best
+}
+
+// SpeciesColor returns the color of a species
+func (g *LifeGame) SpeciesColor(state uint8) RGBAColor {
+	return speciesColors[(int(g.species(state))-1)%len(speciesColors)]
+}
+
+// SpeciesStatus returns the population of each species, for the status bar
+func SpeciesStatus(population []int) string {
+	counts := make([]string, 0, len(population))
+	for _, n := range population[1:] {
+		counts = append(counts, fmt.Sprintf("%d", n))
+	}
+	return " species: " + strings.Join(counts, "/")
+}
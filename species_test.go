@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSpeciesRules(t *testing.T) {
+	keepConfig(t)
+
+	var matrix = []struct {
+		rule    string
+		species int
+	}{
+		{"Immigration", 2},
+		{"QuadLife", 4},
+		{"quad life", 4},
+	}
+	for _, m := range matrix {
+		r, err := LookupRule(m.rule)
+		if err != nil {
+			t.Errorf("%s: %s", m.rule, err)
+			continue
+		}
+		if r.Species != m.species || len(r.Rulestring) > 0 {
+			t.Errorf("%s: expected %d species and no rulestring, got %d %q", m.rule, m.species, r.Species, r.Rulestring)
+		}
+		if _, err := r.MapString(); err == nil {
+			t.Errorf("%s: expected an error writing it as a MAP rule", m.rule)
+		}
+	}
+
+	// The plain rulestring is still Conway's Life
+	if r, err := LookupRule("B3/S23"); err != nil || r.Name != "Conway" || r.Species != 0 {
+		t.Errorf("expected Conway, got %v %s", r, err)
+	}
+
+	// Species rules are saved by their name
+	g := newTestGame(4, 4)
+	if err := g.UseRule("quadlife"); err != nil || g.RuleName() != "QuadLife" {
+		t.Errorf("expected QuadLife, got %s %v", g.RuleName(), err)
+	}
+}
+
+func TestMajoritySpecies(t *testing.T) {
+	keepConfig(t)
+
+	var matrix = []struct {
+		rle   string
+		x, y  int
+		state uint8
+	}{
+		// 3 different parents give birth to the missing species
+		{"x = 3, y = 1, rule = QuadLife\nABC!", 0, -1, 4},
+		{"x = 3, y = 1, rule = QuadLife\nDBA!", 0, 1, 3},
+		// Otherwise the majority wins
+		{"x = 3, y = 1, rule = QuadLife\nCBC!", 0, -1, 3},
+		{"x = 3, y = 1, rule = Immigration\nBAB!", 0, 1, 2},
+		{"x = 3, y = 1, rule = Immigration\nABA!", 0, -1, 1},
+		// Survivors keep their species
+		{"x = 3, y = 1, rule = QuadLife\nABC!", 0, 0, 2},
+		// States past the last species wrap around
+		{"x = 3, y = 1, rule = Immigration\nCCA!", 0, -1, 1},
+	}
+	for _, m := range matrix {
+		g := patternGame(t, 8, 8, m.rle)
+		g.NextFrame()
+		x, y := g.TranslateXY(m.x, m.y)
+		if c := g.cells[y][x]; !c.alive || c.state != m.state {
+			t.Errorf("%q: expected %d,%d to be state %d, got %v %d", m.rle, m.x, m.y, m.state, c.alive, c.state)
+		}
+	}
+}
+
+func TestSpeciesStatus(t *testing.T) {
+	keepConfig(t)
+
+	g := patternGame(t, 8, 8, "x = 3, y = 1, rule = QuadLife\nABC!")
+	g.NextFrame()
+	if !strings.Contains(g.status, "species: 0/1/0/2") {
+		t.Errorf("status does not have the species populations: %q", g.status)
+	}
+	if color, _ := g.CellColor(g.cells[3][4]); color != speciesColors[3] {
+		t.Errorf("expected the 4th species color, got %v", color)
+	}
+
+	// Coloring by age doesn't change the species colors
+	cfg.Color = true
+	if color, _ := g.CellColor(g.cells[3][4]); color != speciesColors[3] {
+		t.Errorf("expected the 4th species color with -color, got %v", color)
+	}
+}
+
+func TestRandomSpecies(t *testing.T) {
+	keepConfig(t)
+	cfg.Seed = 7
+
+	g := newTestGame(20, 20)
+	if err := g.UseRule("QuadLife"); err != nil {
+		t.Fatal(err)
+	}
+	g.InitializeRandomCells()
+	seen := make(map[uint8]bool)
+	for y := range g.cells {
+		for _, c := range g.cells[y] {
+			if c.alive != (c.state != 0) || c.state > 4 {
+				t.Fatalf("cell %d, %d has state %d", c.x, c.y, c.state)
+			}
+			seen[c.state] = true
+		}
+	}
+	if len(seen) != 5 {
+		t.Errorf("expected empty cells and all 4 species, got %v", seen)
+	}
+}
@@ -0,0 +1,184 @@
+// sdl2-life
+// by Brian C. Lane <bcl@brianlane.com>
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"log"
+	"os"
+)
+
+// Y4MWriter writes frames of the world as a YUV4MPEG2 stream
+// The frames are 4:2:0 full range (JPEG) YCbCr, which ffmpeg reads as yuvj420p
+type Y4MWriter struct {
+	out    io.WriteCloser
+	w      *bufio.Writer
+	width  int
+	height int
+	fps    int
+	y      []byte
+	cb     []byte
+	cr     []byte
+	header bool   // The stream header has been written
+	Params string // Extra header parameters, eg. the pattern's metadata
+}
+
+// OpenY4M opens the file to write the video stream to, - is stdout
+// When writing to stdout the game's messages need to be printed somewhere else, see ConsoleWriter.
+func OpenY4M(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return os.Stdout, nil
+	}
+	return os.Create(path)
+}
+
+// ConsoleWriter returns where to print messages for the user
+// It is stderr when the video is written to stdout, so that they don't end up in the video.
+func ConsoleWriter() io.Writer {
+	if cfg.Y4M == "-" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// NewY4MWriter returns a writer for the frames
+// width and height must be even so that the chroma planes are a quarter of the size.
+// The stream header is written with the first frame, so that Params can be set before then.
+func NewY4MWriter(out io.WriteCloser, width, height, fps int) (*Y4MWriter, error) {
+	if width < 2 || height < 2 || width%2 != 0 || height%2 != 0 {
+		return nil, fmt.Errorf("video size must be even, not %dx%d", width, height)
+	}
+	v := &Y4MWriter{
+		out:    out,
+		w:      bufio.NewWriter(out),
+		width:  width,
+		height: height,
+		fps:    fps,
+		y:      make([]byte, width*height),
+		cb:     make([]byte, width*height/4),
+		cr:     make([]byte, width*height/4),
+	}
+	return v, nil
+}
+
+// writeHeader writes the stream header, if it hasn't already been written
+func (v *Y4MWriter) writeHeader() error {
+	if v.header {
+		return nil
+	}
+	v.header = true
+	header := fmt.Sprintf("YUV4MPEG2 W%d H%d F%d:1 Ip A1:1 C420jpeg XYSCSS=420JPEG", v.width, v.height, v.fps)
+	if len(v.Params) > 0 {
+		header += " " + v.Params
+	}
+	_, err := v.w.WriteString(header + "\n")
+	return err
+}
+
+// rotatedPixel returns the pixel at x, y of the image after it has been rotated clockwise
+// by 0, 90, 180, or 270 degrees
+func rotatedPixel(img *image.RGBA, rotate, x, y int) (uint8, uint8, uint8) {
+	w := img.Bounds().Dx()
+	h := img.Bounds().Dy()
+	switch rotate {
+	case 90:
+		x, y = y, h-1-x
+	case 180:
+		x, y = w-1-x, h-1-y
+	case 270:
+		x, y = w-1-y, x
+	}
+	i := img.PixOffset(x, y)
+	return img.Pix[i], img.Pix[i+1], img.Pix[i+2]
+}
+
+// WriteFrame rotates and scales the image to the size of the video and writes it
+func (v *Y4MWriter) WriteFrame(img *image.RGBA, rotate int) error {
+	// Size of the image after it has been rotated
+	rw, rh := img.Bounds().Dx(), img.Bounds().Dy()
+	if rotate == 90 || rotate == 270 {
+		rw, rh = rh, rw
+	}
+
+	// Accumulate the chroma of each 2x2 block as the luma is written
+	cb := make([]int, len(v.cb))
+	cr := make([]int, len(v.cr))
+	for y := 0; y < v.height; y++ {
+		for x := 0; x < v.width; x++ {
+			r, g, b := rotatedPixel(img, rotate, x*rw/v.width, y*rh/v.height)
+			yy, u, vv := color.RGBToYCbCr(r, g, b)
+			v.y[y*v.width+x] = yy
+			i := (y/2)*(v.width/2) + x/2
+			cb[i] += int(u)
+			cr[i] += int(vv)
+		}
+	}
+	for i := range cb {
+		v.cb[i] = uint8(cb[i] / 4)
+		v.cr[i] = uint8(cr[i] / 4)
+	}
+
+	if err := v.writeHeader(); err != nil {
+		return err
+	}
+	if _, err := v.w.WriteString("FRAME\n"); err != nil {
+		return err
+	}
+	for _, plane := range [][]byte{v.y, v.cb, v.cr} {
+		if _, err := v.w.Write(plane); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close flushes the remaining frames and closes the output
+func (v *Y4MWriter) Close() error {
+	err := v.writeHeader()
+	if err == nil {
+		err = v.w.Flush()
+	}
+	if err != nil {
+		v.out.Close()
+		return err
+	}
+	return v.out.Close()
+}
+
+// StartVideo sets up writing the world to a Y4M stream
+// The size of the video defaults to the size of the world, rotated by -rotate
+func (g *LifeGame) StartVideo(out io.WriteCloser) error {
+	width, height := cfg.Y4MWidth, cfg.Y4MHeight
+	if width == 0 || height == 0 {
+		width, height = g.columns*cfg.CellSize, g.rows*cfg.CellSize
+		if cfg.Rotate == 90 || cfg.Rotate == 270 {
+			width, height = height, width
+		}
+		// Round down to an even size
+		width, height = width&^1, height&^1
+	}
+
+	var err error
+	g.video, err = NewY4MWriter(out, width, height, cfg.Fps)
+	return err
+}
+
+// WriteVideoFrame writes the current state of the world to the video
+func (g *LifeGame) WriteVideoFrame() {
+	if g.video == nil {
+		return
+	}
+	if !g.video.header {
+		g.video.Params = y4mParams(g.Metadata())
+	}
+	img := g.RenderImage(cfg.CellSize, cfg.Border, "display")
+	if err := g.video.WriteFrame(img, cfg.Rotate); err != nil {
+		log.Printf("Failed to write video frame, stopping video: %s\n", err)
+		g.video.Close()
+		g.video = nil
+	}
+}
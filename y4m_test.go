@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"os"
+	"testing"
+)
+
+// nopCloser adds a Close method to a bytes.Buffer
+type nopCloser struct {
+	*bytes.Buffer
+}
+
+func (nopCloser) Close() error { return nil }
+
+func TestRotatedPixel(t *testing.T) {
+	// 3x2 image with a red pixel in the top left corner
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	img.Pix[0] = 255
+
+	var matrix = []struct {
+		rotate int
+		x, y   int
+	}{
+		{0, 0, 0},
+		{90, 1, 0},
+		{180, 2, 1},
+		{270, 0, 2},
+	}
+
+	for _, tt := range matrix {
+		if r, _, _ := rotatedPixel(img, tt.rotate, tt.x, tt.y); r != 255 {
+			t.Errorf("rotate %d: expected red pixel at %d,%d", tt.rotate, tt.x, tt.y)
+		}
+	}
+}
+
+func TestY4MWriter(t *testing.T) {
+	if _, err := NewY4MWriter(nopCloser{&bytes.Buffer{}}, 5, 4, 10); err == nil {
+		t.Errorf("odd width did not return an error")
+	}
+
+	var buf bytes.Buffer
+	v, err := NewY4MWriter(nopCloser{&buf}, 4, 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	if err = v.WriteFrame(img, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = v.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	header := "YUV4MPEG2 W4 H2 F10:1 Ip A1:1 C420jpeg XYSCSS=420JPEG\n"
+	expected := header + "FRAME\n" + string(bytes.Repeat([]byte{255}, 8)) + string([]byte{128, 128, 128, 128})
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestConsoleWriter(t *testing.T) {
+	keepConfig(t)
+
+	cfg.Y4M = ""
+	if ConsoleWriter() != os.Stdout {
+		t.Errorf("expected messages on stdout")
+	}
+	// The video is on stdout, so the messages can't be
+	cfg.Y4M = "-"
+	out, err := OpenY4M(cfg.Y4M)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != os.Stdout || ConsoleWriter() != os.Stderr {
+		t.Errorf("expected the video on stdout and messages on stderr")
+	}
+}